@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Struct AuthorizedUser is the application default credentials format for cached oauth user tokens
+type AuthorizedUser struct {
+	Type         string `json:"type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Func default_token_file returns where oauth user tokens are cached between runs
+func default_token_file() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "gsheet/token.json"
+	}
+	return filepath.Join(dir, "web-burner", "token.json")
+}
+
+// Func setup_credentials points the google services at a service account, external account or oauth user credentials.
+// With no credentials file and GOOGLE_APPLICATION_CREDENTIALS unset, application default credentials such as
+// gcloud user credentials or workload identity on the metadata server are used.
+func setup_credentials(credentials string, token_file string) error {
+	if credentials == "" {
+		if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "" {
+			log.Println("No credentials file given, using application default credentials")
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(credentials)
+	if err != nil {
+		return err
+	}
+	var creds map[string]interface{}
+	err = json.Unmarshal(data, &creds)
+	if err != nil {
+		return fmt.Errorf("unable to parse credentials file %s: %v", credentials, err)
+	}
+
+	// OAuth client secrets for an installed app have an "installed" section
+	_, installed := creds["installed"]
+	if !installed {
+		log.Println("Using", creds["type"], "credentials from", credentials)
+		return os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
+	}
+
+	if _, err := os.Stat(token_file); err == nil {
+		log.Println("Using cached oauth token", token_file)
+		return os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", token_file)
+	}
+
+	config, err := google.ConfigFromJSON(data, drive.DriveScope, sheets.SpreadsheetsScope)
+	if err != nil {
+		return err
+	}
+	token, err := oauth_token(config)
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		return fmt.Errorf("oauth flow did not return a refresh token")
+	}
+
+	user := AuthorizedUser{
+		Type:         "authorized_user",
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RefreshToken: token.RefreshToken,
+	}
+	out, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(token_file), 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(token_file, out, 0600)
+	if err != nil {
+		return err
+	}
+	log.Println("Cached oauth token in", token_file)
+	return os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", token_file)
+}
+
+// Func oauth_token runs the installed app flow, accepting the redirect on a loopback listener or the pasted redirect url on stdin
+func oauth_token(config *oauth2.Config) (*oauth2.Token, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	config.RedirectURL = "http://" + ln.Addr().String()
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	state := hex.EncodeToString(b)
+
+	codes := make(chan string, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, err := auth_code(r.URL.Query(), state)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Authorization complete, you can close this window.")
+		select {
+		case codes <- code:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// Headless hosts can paste the url the browser was redirected to
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			u, err := url.Parse(strings.TrimSpace(scanner.Text()))
+			if err != nil {
+				continue
+			}
+			code, err := auth_code(u.Query(), state)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			select {
+			case codes <- code:
+			default:
+			}
+			return
+		}
+	}()
+
+	log.Println("Open the following url in a browser to authorize access to Google Drive and Sheets.")
+	log.Println("If the browser cannot reach this host, paste the url it was redirected to here.")
+	fmt.Println(config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce))
+
+	select {
+	case code := <-codes:
+		return config.Exchange(context.TODO(), code)
+	case <-time.After(5 * time.Minute):
+		return nil, fmt.Errorf("timed out waiting for oauth authorization")
+	}
+}
+
+// Func auth_code checks the state of an oauth redirect and returns its code
+func auth_code(query url.Values, state string) (string, error) {
+	if query.Get("state") != state {
+		return "", fmt.Errorf("oauth state does not match")
+	}
+	if e := query.Get("error"); e != "" {
+		return "", fmt.Errorf("oauth authorization failed: %s", e)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("no oauth code found")
+	}
+	return code, nil
+}
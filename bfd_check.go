@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interface PodExec runs a command in a pod container so fakes can replace oc exec in tests
+type PodExec interface {
+	Exec(ctx context.Context, namespace string, pod string, container string, command ...string) ([]byte, error)
+}
+
+// Func Exec runs a command in a pod container with oc exec
+func (c *OcCluster) Exec(ctx context.Context, namespace string, pod string, container string, command ...string) ([]byte, error) {
+	return c.oc(ctx, nil, append([]string{"exec", "-n", namespace, pod, "-c", container, "--"}, command...)...)
+}
+
+// Container of the serving pods running frr
+const bfd_container = "bfd"
+
+// Interval between bfd session checks while waiting for sessions to come up
+var bfd_poll_interval = 30 * time.Second
+
+// Default fraction of bfd sessions that must be up after the serving init job
+const default_bfd_min_up = 0.95
+
+// Struct BFDPeer is a session in the output of vtysh show bfd peers json
+type BFDPeer struct {
+	Peer      string `json:"peer"`
+	Interface string `json:"interface"`
+	Status    string `json:"status"`
+}
+
+// Struct BFDPeerCounters is a session in the output of vtysh show bfd peers counters json
+type BFDPeerCounters struct {
+	Peer              string `json:"peer"`
+	SessionDownEvents int    `json:"session-down-events"`
+}
+
+// Struct BFDNamespace is the bfd session health of the serving pods in a namespace
+type BFDNamespace struct {
+	Namespace string `json:"namespace"`
+	Pods      int    `json:"pods"`
+	Sessions  int    `json:"sessions"`
+	Up        int    `json:"up"`
+	Down      int    `json:"down"`
+	Flaps     int    `json:"flaps"` // session down events since frr started
+}
+
+// Struct BFDReport is the bfd check result recorded in the run state
+type BFDReport struct {
+	Checked    time.Time      `json:"checked"`
+	Namespaces []BFDNamespace `json:"namespaces"`
+}
+
+// Func totals sums the sessions of every namespace
+func (r BFDReport) totals() (sessions int, up int, flaps int) {
+	for _, n := range r.Namespaces {
+		sessions += n.Sessions
+		up += n.Up
+		flaps += n.Flaps
+	}
+	return sessions, up, flaps
+}
+
+// Func up_fraction returns the fraction of sessions that are up, 0 without sessions
+func (r BFDReport) up_fraction() float64 {
+	sessions, up, _ := r.totals()
+	if sessions == 0 {
+		return 0
+	}
+	return float64(up) / float64(sessions)
+}
+
+// Func serving_pods lists the serving pods running frr, keyed by serving namespace
+func serving_pods(ctx context.Context, cluster Cluster) (map[string][]string, error) {
+	objects, err := cluster.ListObjects(ctx, "pods", "serving")
+	if err != nil {
+		return nil, err
+	}
+	pods := make(map[string][]string)
+	for _, o := range objects {
+		parts := strings.SplitN(o, "/", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "serving-ns-") {
+			continue
+		}
+		pods[parts[0]] = append(pods[parts[0]], parts[1])
+	}
+	return pods, nil
+}
+
+// Func check_bfd reads the bfd sessions of every serving pod with vtysh
+func check_bfd(ctx context.Context, cluster Cluster, exec PodExec) (BFDReport, error) {
+	report := BFDReport{Checked: time.Now()}
+	pods, err := serving_pods(ctx, cluster)
+	if err != nil {
+		return report, err
+	}
+	var namespaces []string
+	for ns := range pods {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		n := BFDNamespace{Namespace: ns, Pods: len(pods[ns])}
+		for _, pod := range pods[ns] {
+			out, err := exec.Exec(ctx, ns, pod, bfd_container, "vtysh", "-c", "show bfd peers json")
+			if err != nil {
+				return report, err
+			}
+			var peers []BFDPeer
+			err = json.Unmarshal(out, &peers)
+			if err != nil {
+				return report, fmt.Errorf("unable to parse bfd peers of pod %s/%s: %v", ns, pod, err)
+			}
+			for _, p := range peers {
+				n.Sessions++
+				if p.Status == "up" {
+					n.Up++
+				} else {
+					n.Down++
+				}
+			}
+
+			out, err = exec.Exec(ctx, ns, pod, bfd_container, "vtysh", "-c", "show bfd peers counters json")
+			if err != nil {
+				return report, err
+			}
+			var counters []BFDPeerCounters
+			err = json.Unmarshal(out, &counters)
+			if err != nil {
+				return report, fmt.Errorf("unable to parse bfd counters of pod %s/%s: %v", ns, pod, err)
+			}
+			for _, c := range counters {
+				n.Flaps += c.SessionDownEvents
+			}
+		}
+		report.Namespaces = append(report.Namespaces, n)
+	}
+	return report, nil
+}
+
+// Func bfd_check waits until enough bfd sessions of the serving pods are up and records them in the run state
+func (o *Orchestrator) bfd_check(ctx context.Context) error {
+	if !o.Config.BFD {
+		log_fields("bfd disabled, skipping session check")
+		return nil
+	}
+	min_up := o.Config.BFDMinUp
+	for {
+		report, err := check_bfd(ctx, o.Cluster, o.Exec)
+		if err != nil {
+			return err
+		}
+		sessions, up, flaps := report.totals()
+		log_fields("bfd sessions", "namespaces", len(report.Namespaces), "sessions", sessions, "up", up, "flaps", flaps)
+		o.State.BFD = &report
+		err = o.State.save()
+		if err != nil {
+			return err
+		}
+		if sessions > 0 && report.up_fraction() >= min_up {
+			return nil
+		}
+		select {
+		case <-time.After(bfd_poll_interval):
+		case <-ctx.Done():
+			if sessions == 0 {
+				return fmt.Errorf("no bfd sessions found on the serving pods")
+			}
+			return fmt.Errorf("%d of %d bfd sessions up, below the required fraction %.2f", up, sessions, min_up)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Workload configs, object templates, metrics profiles and topology specs shipped in the binary. Templates are listed
+// so runtime_templates such as the generated kubeconfig secret are never embedded
+//
+//go:embed workload topology objectTemplates/*.yml objectTemplates/cm_frr.yaml
+var embedded_bundle embed.FS
+
+// Env var naming a directory with workload/ and objectTemplates/ used instead of the embedded bundle
+const bundle_env = "WEB_BURNER_BUNDLE"
+
+// Files a run needs besides the workload config and its object templates
+var run_files = []string{"workload/cfg_icni2_serving_resource_init.yml", "workload/sriov_policy.yaml", "workload/metrics_full.yaml"}
+
+// Object template paths referenced by a workload config, including both branches of conditionals
+var template_ref = regexp.MustCompile(`objectTemplates/[\w.-]+`)
+
+// Func use_bundle_dir changes to the bundle directory given in WEB_BURNER_BUNDLE so it is used like a checkout
+func use_bundle_dir() error {
+	dir := os.Getenv(bundle_env)
+	if dir == "" {
+		return nil
+	}
+	return os.Chdir(dir)
+}
+
+// Func bundle_file reads a file from disk, falling back to the embedded bundle for relative paths not on disk
+func bundle_file(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil || !os.IsNotExist(err) || filepath.IsAbs(path) {
+		return data, err
+	}
+	embedded, e := embedded_bundle.ReadFile(filepath.ToSlash(filepath.Clean(path)))
+	if e != nil {
+		return nil, err
+	}
+	return embedded, nil
+}
+
+// Func bundle_glob lists files matching a pattern on disk and in the embedded bundle, a file on disk replacing the embedded one of the same name
+func bundle_glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil || filepath.IsAbs(pattern) {
+		return matches, err
+	}
+	// A partial directory, e.g. the files materialize wrote for a run, must not hide the rest of the bundle
+	embedded, err := fs.Glob(embedded_bundle, filepath.ToSlash(filepath.Clean(pattern)))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range embedded {
+		m = filepath.FromSlash(m)
+		if !exists(matches, m) {
+			matches = append(matches, m)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Func bundle_source tells if a file or directory of the bundle is read from disk or from the binary
+func bundle_source(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return "disk"
+	}
+	return "embedded"
+}
+
+// Func workload_name returns the name of a workload config, workload/cfg_<name>.yml
+func workload_name(config string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(config), "cfg_"), ".yml")
+}
+
+// Func workload_config returns the config of a workload name, or the name when it is already a path
+func workload_config(name string) string {
+	if strings.Contains(name, "/") || strings.HasSuffix(name, ".yml") {
+		return name
+	}
+	return "workload/cfg_" + name + ".yml"
+}
+
+// Func workload_files returns a workload config and the object templates it references
+func workload_files(config string) ([]string, error) {
+	data, err := bundle_file(config)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{config}
+	for _, ref := range template_ref.FindAllString(string(data), -1) {
+		if !exists(files, ref) {
+			files = append(files, ref)
+		}
+	}
+	return files, nil
+}
+
+// Func export_files writes bundle files under dir, keeping existing files unless overwrite is set
+func export_files(files []string, dir string, overwrite bool) ([]string, error) {
+	var written []string
+	for _, f := range files {
+		// Generated during a run from the cluster credentials, never copied
+		if exists(runtime_templates, f) {
+			continue
+		}
+		dst := filepath.Join(dir, f)
+		if _, err := os.Stat(dst); err == nil && !overwrite {
+			continue
+		}
+		data, err := bundle_file(f)
+		if err != nil {
+			return written, err
+		}
+		err = os.MkdirAll(filepath.Dir(dst), 0755)
+		if err != nil {
+			return written, err
+		}
+		err = os.WriteFile(dst, data, 0644)
+		if err != nil {
+			return written, err
+		}
+		written = append(written, dst)
+	}
+	return written, nil
+}
+
+// Func materialize writes the files kube-burner reads for a config to the working directory when they are only embedded
+func materialize(config string, extra ...string) error {
+	files, err := workload_files(config)
+	if err != nil {
+		return err
+	}
+	written, err := export_files(append(files, extra...), ".", false)
+	for _, f := range written {
+		log_fields("wrote embedded workload file", "file", f)
+	}
+	return err
+}
+
+// Func workloads_cmd lists the workloads of the bundle or exports one with its templates
+func workloads_cmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: workloads list | workloads export <name|all> <dir> [-overwrite]")
+	}
+	switch args[0] {
+	case "list":
+		return workloads_list()
+	case "export":
+		fs := flag.NewFlagSet("workloads export", flag.ExitOnError)
+		overwrite := fs.Bool("overwrite", false, "replace files already in the directory")
+		// Flags may follow the name and directory
+		var pos []string
+		rest := args[1:]
+		for len(rest) > 0 {
+			fs.Parse(rest)
+			if fs.NArg() == 0 {
+				break
+			}
+			pos = append(pos, fs.Arg(0))
+			rest = fs.Args()[1:]
+		}
+		if len(pos) != 2 {
+			return fmt.Errorf("usage: workloads export <name|all> <dir> [-overwrite]")
+		}
+		return workloads_export(pos[0], pos[1], *overwrite)
+	}
+	return fmt.Errorf("unknown workloads command %q, use list or export", args[0])
+}
+
+// Func workloads_list prints the workload configs with the object templates they use
+func workloads_list() error {
+	configs, err := bundle_glob("workload/cfg_*.yml")
+	if err != nil {
+		return err
+	}
+	sort.Strings(configs)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCONFIG\tTEMPLATES\tSOURCE")
+	for _, c := range configs {
+		files, err := workload_files(c)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", workload_name(c), c, len(files)-1, bundle_source(c))
+	}
+	return w.Flush()
+}
+
+// Func workloads_export writes a workload, or every bundle file for all, with the files a run needs to dir
+func workloads_export(name string, dir string, overwrite bool) error {
+	var files []string
+	if name == "all" {
+		err := fs.WalkDir(embedded_bundle, ".", func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				files = append(files, path)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		// Files on disk override the embedded copies, and files only on disk are exported too
+		for _, pattern := range []string{"workload/*", "objectTemplates/*", "topology/*"} {
+			if bundle_source(filepath.Dir(pattern)) != "disk" {
+				continue
+			}
+			m, err := filepath.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			for _, f := range m {
+				if !exists(files, f) {
+					files = append(files, f)
+				}
+			}
+		}
+	} else {
+		config := workload_config(name)
+		var err error
+		files, err = workload_files(config)
+		if err != nil {
+			return fmt.Errorf("unknown workload %s: %v", name, err)
+		}
+		for _, f := range run_files {
+			if f == config {
+				continue
+			}
+			extra, err := workload_files(f)
+			if err != nil {
+				return err
+			}
+			for _, e := range extra {
+				if !exists(files, e) {
+					files = append(files, e)
+				}
+			}
+		}
+	}
+	written, err := export_files(files, dir, overwrite)
+	if err != nil {
+		return err
+	}
+	log_fields("exported workload", "name", name, "dir", dir, "files", len(written), "skipped", len(files)-len(written))
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBundleExcludesRuntimeTemplates(t *testing.T) {
+	for _, f := range runtime_templates {
+		if _, err := fs.Stat(embedded_bundle, f); err == nil {
+			t.Errorf("%s is embedded in the binary", f)
+		}
+	}
+	// Every object template a workload references is embedded
+	configs, err := fs.Glob(embedded_bundle, "workload/cfg_*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range configs {
+		files, err := workload_files(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if _, err := fs.Stat(embedded_bundle, f); err != nil && !exists(runtime_templates, f) {
+				t.Errorf("%s referenced by %s is not embedded", f, c)
+			}
+		}
+	}
+}
+
+func TestExportSkipsRuntimeTemplates(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in_temp_dir(t)
+	// A checkout after a run, with the generated kubeconfig secret next to the templates
+	for _, d := range []string{"workload", "topology"} {
+		err := os.Symlink(filepath.Join(wd, d), d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	secret := filepath.Join(t.TempDir(), "secret_kubeconfig.yaml")
+	err = os.WriteFile(secret, []byte("kind: Secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir("objectTemplates", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := filepath.Glob(filepath.Join(wd, "objectTemplates", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range append(templates, secret) {
+		err := os.Symlink(f, filepath.Join("objectTemplates", filepath.Base(f)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"all", "icni2_serving_resource_init"} {
+		out := t.TempDir()
+		err := workloads_export(name, out, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(out, "objectTemplates", "secret_kubeconfig.yaml")); err == nil {
+			t.Errorf("workloads export %s wrote the kubeconfig secret", name)
+		}
+		if _, err := os.Stat(filepath.Join(out, "objectTemplates", "cm_frr.yaml")); err != nil {
+			t.Errorf("workloads export %s did not write cm_frr.yaml: %v", name, err)
+		}
+	}
+}
+
+func TestBundleGlobPartialDir(t *testing.T) {
+	embedded, err := fs.Glob(embedded_bundle, "workload/cfg_*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in_temp_dir(t)
+	// What run leaves in the working directory, plus a config only on disk
+	capture_log(t)
+	err = materialize("workload/cfg_icni2_node_density2.yml", run_files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write_file(t, "workload/cfg_local.yml", "jobs: []\n")
+	write_file(t, "workload/cfg_icni2_cluster_density2.yml", "jobs: []\n")
+
+	configs, err := bundle_glob("workload/cfg_*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]string{"workload/cfg_local.yml"}, embedded...)
+	sort.Strings(want)
+	if !reflect.DeepEqual(configs, want) {
+		t.Errorf("got %v\nwant %v", configs, want)
+	}
+	// The config on disk replaces the embedded one
+	data, err := bundle_file("workload/cfg_icni2_cluster_density2.yml")
+	if err != nil || string(data) != "jobs: []\n" {
+		t.Errorf("read %q, %v from the bundle, want the file on disk", data, err)
+	}
+	if bundle_source("workload/cfg_local.yml") != "disk" || bundle_source("workload/cfg_regular_node_density.yml") != "embedded" {
+		t.Error("bundle_source does not tell disk and embedded files apart")
+	}
+
+	templates, err := bundle_glob("./objectTemplates/*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := fs.Glob(embedded_bundle, "objectTemplates/*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(templates, all) {
+		t.Errorf("got templates %v\nwant %v", templates, all)
+	}
+
+	abs, err := bundle_glob(filepath.Join(t.TempDir(), "*.yml"))
+	if err != nil || len(abs) != 0 {
+		t.Errorf("got %v, %v for an empty directory outside the bundle", abs, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Directory holding per uuid run state
+const runs_dir = "runs"
+
+// Struct PhaseRecord records when a phase of a run completed
+type PhaseRecord struct {
+	Name      string    `json:"name"`
+	Started   time.Time `json:"started"`
+	Completed time.Time `json:"completed"`
+}
+
+// Struct PhaseKubeBurner is a kube-burner invocation of a phase
+type PhaseKubeBurner struct {
+	Phase string `json:"phase"`
+	KubeBurnerResult
+}
+
+// Struct RunState is the checkpoint of a run, saved after every phase so it can be resumed
+type RunState struct {
+	UUID       string            `json:"uuid"`
+	Config     RunConfig         `json:"config"`
+	Completed  []PhaseRecord     `json:"completed"`
+	Labels     []LabelChange     `json:"labels,omitempty"`     // node labels changed by this run, restored by cleanup
+	BFD        *BFDReport        `json:"bfd,omitempty"`        // last bfd session check after the serving init job
+	KubeBurner []PhaseKubeBurner `json:"kubeBurner,omitempty"` // every kube-burner invocation of the run
+	Failed     string            `json:"failed,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Func state_file returns the path of the state file for a uuid
+func state_file(uuid string) string {
+	return filepath.Join(runs_dir, uuid, "state.json")
+}
+
+// Func load_state reads the state file of a previous run
+func load_state(uuid string) (*RunState, error) {
+	data, err := os.ReadFile(state_file(uuid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no state found for uuid %s in %s", uuid, state_file(uuid))
+		}
+		return nil, err
+	}
+	var state RunState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Func save writes the state file atomically so an interrupted write does not lose earlier checkpoints
+func (s *RunState) save() error {
+	f := state_file(s.UUID)
+	err := os.MkdirAll(filepath.Dir(f), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, f)
+}
+
+// Func done checks if a phase already completed
+func (s *RunState) done(phase string) bool {
+	for _, p := range s.Completed {
+		if p.Name == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// Func complete records a completed phase and clears any previous failure
+func (s *RunState) complete(phase string, started time.Time) error {
+	s.Completed = append(s.Completed, PhaseRecord{Name: phase, Started: started, Completed: time.Now()})
+	s.Failed = ""
+	s.Error = ""
+	return s.save()
+}
+
+// Func fail records the phase a run failed in
+func (s *RunState) fail(phase string, err error) error {
+	s.Failed = phase
+	s.Error = err.Error()
+	return s.save()
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Struct DeleteObject is an object kind removed by the delete job
+type DeleteObject struct {
+	Kind       string
+	APIVersion string
+	Resource   string // resource name used with oc get
+}
+
+// Objects carrying the kube-burner-uuid label that are always removed
+var delete_objects = []DeleteObject{
+	{Kind: "Deployment", APIVersion: "apps/v1", Resource: "deployments"},
+	{Kind: "Pod", APIVersion: "v1", Resource: "pods"},
+	{Kind: "Secret", APIVersion: "v1", Resource: "secrets"},
+	{Kind: "ConfigMap", APIVersion: "v1", Resource: "configmaps"},
+	{Kind: "Service", APIVersion: "v1", Resource: "services"},
+}
+
+var sriov_network_object = DeleteObject{Kind: "SriovNetwork", APIVersion: "sriovnetwork.openshift.io/v1", Resource: "sriovnetworks"}
+var namespace_object = DeleteObject{Kind: "Namespace", APIVersion: "v1", Resource: "namespaces"}
+
+// Template of the kube-burner delete job, one job per uuid
+var delete_job_template = template.Must(template.New("cfg_del").Parse(`jobs:
+{{- range $uuid := .UUIDs }}
+  - name: remove-objects-{{ $uuid }}
+    jobType: delete
+    qps: {{ $.QPS }}
+    burst: {{ $.Burst }}
+    objects:
+{{- range $.Objects }}
+      - kind: {{ .Kind }}
+        labelSelector: {kube-burner-uuid: {{ $uuid }}}
+        apiVersion: {{ .APIVersion }}
+{{- end }}
+{{- end }}
+`))
+
+// Struct DeleteResult is what cleanup found and removed for one kind
+type DeleteResult struct {
+	Kind      string
+	Found     int
+	Remaining int
+}
+
+// Func delete_job renders the kube-burner delete job for the given uuids and object kinds
+func delete_job(uuids []string, objects []DeleteObject, qps int, burst int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := delete_job_template.Execute(&buf, struct {
+		UUIDs   []string
+		Objects []DeleteObject
+		QPS     int
+		Burst   int
+	}{uuids, objects, qps, burst})
+	return buf.Bytes(), err
+}
+
+// Func count_objects counts the objects of each kind carrying any of the uuids
+func count_objects(ctx context.Context, cluster Cluster, uuids []string, objects []DeleteObject) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, o := range objects {
+		for _, u := range uuids {
+			found, err := cluster.ListObjects(ctx, o.Resource, "kube-burner-uuid="+u)
+			if err != nil {
+				return nil, err
+			}
+			counts[o.Kind] += len(found)
+		}
+	}
+	return counts, nil
+}
+
+// Func cleanup_cmd deletes the objects created by a run and removes the worker-spk labels it added
+func cleanup_cmd(args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	uuid := fs.String("uuid", "", "uuid of the run to clean up")
+	namespaces := fs.Bool("namespaces", false, "also delete namespaces created by the run")
+	sriov := fs.Bool("sriov", false, "also delete sriov networks created by the run")
+	serving := fs.Bool("serving", true, "also delete objects of the serving init job recorded for the run")
+	unlabel := fs.Bool("unlabel", true, "restore node labels changed by the run")
+	qps := fs.Int("qps", env_int("QPS", 20), "kube-burner qps of the delete job")
+	burst := fs.Int("burst", env_int("BURST", 20), "kube-burner burst of the delete job")
+	kubeconfig := fs.String("kubeconfig", env_default("KUBECONFIG", "/home/kni/clusterconfigs/auth/kubeconfig"), "kubeconfig of the cluster")
+	dry_run := fs.Bool("dry-run", false, "only write the delete job and report matching objects")
+	var burner ExecKubeBurner
+	kube_burner_flags(fs, &burner)
+	fs.Parse(args)
+
+	if *uuid == "" {
+		return fmt.Errorf("please provide the uuid of the run using flag '-uuid'")
+	}
+
+	cluster := &OcCluster{Kubeconfig: *kubeconfig}
+	c := &Cleanup{
+		UUID:       *uuid,
+		Namespaces: *namespaces,
+		Sriov:      *sriov,
+		Serving:    *serving,
+		Unlabel:    *unlabel,
+		QPS:        *qps,
+		Burst:      *burst,
+		DryRun:     *dry_run,
+		Cluster:    cluster,
+		Burner:     burner,
+	}
+	results, unlabeled, err := c.Run(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tFOUND\tDELETED\tREMAINING")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", r.Kind, r.Found, r.Found-r.Remaining, r.Remaining)
+	}
+	for _, n := range unlabeled {
+		fmt.Fprintf(w, "node/%s\trestored labels\t\t\n", n)
+	}
+	return w.Flush()
+}
+
+// Struct Cleanup removes what a run created
+type Cleanup struct {
+	UUID       string
+	Namespaces bool
+	Sriov      bool
+	Serving    bool
+	Unlabel    bool
+	QPS        int
+	Burst      int
+	DryRun     bool
+	Cluster    Cluster
+	Burner     KubeBurner
+}
+
+// Func Run deletes the run objects with a kube-burner delete job, returning per kind counts and the nodes unlabeled
+func (c *Cleanup) Run(ctx context.Context) ([]DeleteResult, []string, error) {
+	uuids := []string{c.UUID}
+	state, err := load_state(c.UUID)
+	if err != nil {
+		log_fields("no run state found, only objects labeled with the uuid are removed", "uuid", c.UUID)
+	}
+	if c.Serving && state != nil && state.Config.ServingUUID != "" {
+		uuids = append(uuids, state.Config.ServingUUID)
+	}
+
+	objects := append([]DeleteObject{}, delete_objects...)
+	if c.Sriov {
+		objects = append(objects, sriov_network_object)
+	}
+	if c.Namespaces {
+		objects = append(objects, namespace_object)
+	}
+
+	job, err := delete_job(uuids, objects, c.QPS, c.Burst)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := filepath.Join(runs_dir, c.UUID, "cfg_del.yml")
+	err = os.MkdirAll(filepath.Dir(cfg), 0755)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = os.WriteFile(cfg, job, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	log_fields("wrote delete job", "file", cfg, "uuids", uuids)
+
+	before, err := count_objects(ctx, c.Cluster, uuids, objects)
+	if err != nil {
+		return nil, nil, err
+	}
+	after := before
+	var unlabeled []string
+
+	if !c.DryRun {
+		del_uuid, err := new_uuid()
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = c.Burner.Init(ctx, KubeBurnerOptions{Config: cfg, UUID: del_uuid, LogFile: filepath.Join(runs_dir, c.UUID, "kube-burner-cleanup.log")})
+		if err != nil {
+			return nil, nil, err
+		}
+		after, err = count_objects(ctx, c.Cluster, uuids, objects)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if c.Unlabel && state != nil {
+			labeler := &NodeLabeler{Cluster: c.Cluster, State: state}
+			unlabeled, err = labeler.Restore(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var results []DeleteResult
+	for _, o := range objects {
+		results = append(results, DeleteResult{Kind: o.Kind, Found: before[o.Kind], Remaining: after[o.Kind]})
+	}
+	return results, unlabeled, nil
+}
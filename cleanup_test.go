@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeleteJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		uuids   []string
+		objects []DeleteObject
+		want    string
+	}{
+		{"labeled objects", []string{"u1"}, delete_objects, `jobs:
+  - name: remove-objects-u1
+    jobType: delete
+    qps: 20
+    burst: 30
+    objects:
+      - kind: Deployment
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: apps/v1
+      - kind: Pod
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+      - kind: Secret
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+      - kind: ConfigMap
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+      - kind: Service
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+`},
+		{"namespaces and sriov networks", []string{"u1", "serving-u1"}, []DeleteObject{delete_objects[1], sriov_network_object, namespace_object}, `jobs:
+  - name: remove-objects-u1
+    jobType: delete
+    qps: 20
+    burst: 30
+    objects:
+      - kind: Pod
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+      - kind: SriovNetwork
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: sriovnetwork.openshift.io/v1
+      - kind: Namespace
+        labelSelector: {kube-burner-uuid: u1}
+        apiVersion: v1
+  - name: remove-objects-serving-u1
+    jobType: delete
+    qps: 20
+    burst: 30
+    objects:
+      - kind: Pod
+        labelSelector: {kube-burner-uuid: serving-u1}
+        apiVersion: v1
+      - kind: SriovNetwork
+        labelSelector: {kube-burner-uuid: serving-u1}
+        apiVersion: sriovnetwork.openshift.io/v1
+      - kind: Namespace
+        labelSelector: {kube-burner-uuid: serving-u1}
+        apiVersion: v1
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := delete_job(tt.uuids, tt.objects, 20, 30)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Func cleanup_cluster returns a cluster with pods and namespaces of run u1 and its serving init job
+func cleanup_cluster() *FakeCluster {
+	return &FakeCluster{Objects: map[string][]string{
+		"pods kube-burner-uuid=u1":                  {"served-ns-1/pod-1", "served-ns-2/pod-1"},
+		"pods kube-burner-uuid=serving-u1":          {"serving-ns-1/serving-1"},
+		"namespaces kube-burner-uuid=u1":            {"served-ns-1", "served-ns-2"},
+		"namespaces kube-burner-uuid=serving-u1":    {"serving-ns-1"},
+		"sriovnetworks kube-burner-uuid=serving-u1": {"openshift-sriov-network-operator/sriov-net-1"},
+		"pods kube-burner-uuid=other":               {"served-ns-9/pod-1"},
+	}}
+}
+
+// Func found returns the found count per kind of cleanup results
+func found(results []DeleteResult) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Kind] = r.Found
+	}
+	return counts
+}
+
+func TestCleanupDryRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   bool
+		serving bool
+		want    map[string]int
+		uuids   []string
+	}{
+		{"serving uuid from state", true, true, map[string]int{"Deployment": 0, "Pod": 3, "Secret": 0, "ConfigMap": 0, "Service": 0, "SriovNetwork": 1, "Namespace": 3}, []string{"u1", "serving-u1"}},
+		{"serving disabled", true, false, map[string]int{"Deployment": 0, "Pod": 2, "Secret": 0, "ConfigMap": 0, "Service": 0, "SriovNetwork": 0, "Namespace": 2}, []string{"u1"}},
+		{"no state", false, true, map[string]int{"Deployment": 0, "Pod": 2, "Secret": 0, "ConfigMap": 0, "Service": 0, "SriovNetwork": 0, "Namespace": 2}, []string{"u1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in_temp_dir(t)
+			if tt.state {
+				state := &RunState{UUID: "u1", Config: RunConfig{UUID: "u1", ServingUUID: "serving-u1"}, Labels: []LabelChange{{Node: "worker-a", Label: worker_spk_label}}}
+				if err := state.save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cluster := cleanup_cluster()
+			burner := &FakeKubeBurner{}
+			c := &Cleanup{UUID: "u1", Namespaces: true, Sriov: true, Serving: tt.serving, Unlabel: true, QPS: 20, Burst: 20, DryRun: true, Cluster: cluster, Burner: burner}
+
+			results, unlabeled, err := c.Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := found(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+			if len(burner.Configs) != 0 || len(unlabeled) != 0 || len(cluster.Labels) != 0 {
+				t.Errorf("dry run ran kube-burner %v or restored labels %v", burner.Configs, cluster.Labels)
+			}
+			job, err := os.ReadFile(filepath.Join(runs_dir, "u1", "cfg_del.yml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := delete_job(tt.uuids, append(append([]DeleteObject{}, delete_objects...), sriov_network_object, namespace_object), 20, 20)
+			if string(job) != string(want) {
+				t.Errorf("wrote delete job\n%s\nwant\n%s", job, want)
+			}
+		})
+	}
+}
+
+func TestCleanupRun(t *testing.T) {
+	in_temp_dir(t)
+	state := &RunState{UUID: "u1", Config: RunConfig{UUID: "u1", ServingUUID: "serving-u1"}, Labels: []LabelChange{{Node: "worker-a", Label: worker_spk_label}}}
+	if err := state.save(); err != nil {
+		t.Fatal(err)
+	}
+	cluster := cleanup_cluster()
+	burner := &FakeKubeBurner{}
+	c := &Cleanup{UUID: "u1", Serving: true, Unlabel: true, QPS: 20, Burst: 20, Cluster: cluster, Burner: burner}
+
+	results, unlabeled, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(delete_objects) {
+		t.Errorf("got results for %d kinds without -namespaces and -sriov, want %d", len(results), len(delete_objects))
+	}
+	if !reflect.DeepEqual(burner.Configs, []string{filepath.Join(runs_dir, "u1", "cfg_del.yml")}) {
+		t.Errorf("ran kube-burner with %v", burner.Configs)
+	}
+	if !reflect.DeepEqual(unlabeled, []string{"worker-a"}) || !reflect.DeepEqual(cluster.Labels, []string{"worker-a " + worker_spk_label + "-"}) {
+		t.Errorf("restored %v with %v, want worker-a unlabeled", unlabeled, cluster.Labels)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Label marking the nodes that host the fake spk serving pods
+const worker_spk_label = "node-role.kubernetes.io/worker-spk"
+
+// Struct Node is a cluster node with the roles taken from its node-role labels
+type Node struct {
+	Name   string
+	Roles  []string
+	Ready  bool
+	Labels map[string]string
+}
+
+// Func has_role checks if the node carries a node-role label
+func (n Node) has_role(role string) bool {
+	return exists(n.Roles, role)
+}
+
+// Interface Cluster is the set of cluster operations used by the orchestrator so fakes can replace oc in tests
+type Cluster interface {
+	Nodes(ctx context.Context) ([]Node, error)
+	LabelNode(ctx context.Context, node string, label string) error
+	SriovPF(ctx context.Context, node string) (string, error)
+	Apply(ctx context.Context, manifest []byte) error
+	WaitMCPsUpdated(ctx context.Context) error
+	PrometheusURL(ctx context.Context) (string, error)
+	PrometheusToken(ctx context.Context) (string, error)
+	KubeconfigSecret(ctx context.Context) ([]byte, error)
+	ListObjects(ctx context.Context, resource string, selector string) ([]string, error)
+	MachineConfigPools(ctx context.Context) ([]MachineConfigPool, error)
+	OperatorCSVs(ctx context.Context, namespace string) ([]OperatorCSV, error)
+	Namespaces(ctx context.Context, selector string) ([]Namespace, error)
+	LabeledObjects(ctx context.Context, resource string, selector string, key string) ([]LabeledObject, error)
+	DeleteObjects(ctx context.Context, resource string, objects []string) error
+	NodePods(ctx context.Context) (map[string]int, error)
+}
+
+// Struct LabeledObject is an object with the value of one of its labels, empty when unset
+type LabeledObject struct {
+	Object string // namespace/name, or name for cluster scoped resources
+	Value  string
+}
+
+// Struct MachineConfigPool is the status of a machine config pool
+type MachineConfigPool struct {
+	Name     string
+	Updated  bool
+	Updating bool
+	Degraded bool
+}
+
+// Struct OperatorCSV is the phase of an operator cluster service version
+type OperatorCSV struct {
+	Name  string
+	Phase string
+}
+
+// Struct Namespace is a namespace with its labels
+type Namespace struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Struct OcCluster implements Cluster with the oc cli and ssh to the nodes
+type OcCluster struct {
+	Kubeconfig string
+	SSHKey     string
+}
+
+// Func oc runs an oc command against the configured kubeconfig and returns its stdout
+func (c *OcCluster) oc(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "oc", args...)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+c.Kubeconfig)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("oc %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Func Nodes lists cluster nodes with their roles and readiness
+func (c *OcCluster) Nodes(ctx context.Context) ([]Node, error) {
+	out, err := c.oc(ctx, nil, "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parse_nodes(out)
+}
+
+// Func parse_nodes reads the nodes of oc get nodes -o json
+func parse_nodes(data []byte) ([]Node, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []Node
+	for _, item := range list.Items {
+		n := Node{Name: item.Metadata.Name, Labels: item.Metadata.Labels}
+		for l := range item.Metadata.Labels {
+			if strings.HasPrefix(l, "node-role.kubernetes.io/") {
+				n.Roles = append(n.Roles, strings.TrimPrefix(l, "node-role.kubernetes.io/"))
+			}
+		}
+		for _, cond := range item.Status.Conditions {
+			if cond.Type == "Ready" && cond.Status == "True" {
+				n.Ready = true
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// Func LabelNode sets a label given as key=value, or key for an empty value, on a node
+func (c *OcCluster) LabelNode(ctx context.Context, node string, label string) error {
+	if !strings.Contains(label, "=") && !strings.HasSuffix(label, "-") {
+		label = label + "="
+	}
+	_, err := c.oc(ctx, nil, "label", "node", node, label, "--overwrite=true")
+	return err
+}
+
+// Func SriovPF finds the physical function attached to br-ex on a node over ssh
+func (c *OcCluster) SriovPF(ctx context.Context, node string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ssh", "-i", c.SSHKey, "-o", "StrictHostKeyChecking=no", "core@"+node, "sudo ovs-vsctl list-ports br-ex | head -1")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to find sr-iov pf on node %s: %v", node, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Func Apply applies a manifest with oc apply
+func (c *OcCluster) Apply(ctx context.Context, manifest []byte) error {
+	_, err := c.oc(ctx, manifest, "apply", "-f", "-")
+	return err
+}
+
+// Func WaitMCPsUpdated waits until every machine config pool reports Updated or the context expires
+func (c *OcCluster) WaitMCPsUpdated(ctx context.Context) error {
+	out, err := c.oc(ctx, nil, "get", "mcp", "-o", "name")
+	if err != nil {
+		return err
+	}
+	timeout := time.Hour
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	for _, mcp := range strings.Fields(string(out)) {
+		_, err := c.oc(ctx, nil, "wait", "--for=condition=Updated", "--timeout="+timeout.Round(time.Second).String(), mcp)
+		if err != nil {
+			return err
+		}
+		log_fields("machine config pool updated", "mcp", mcp)
+	}
+	return nil
+}
+
+// Func PrometheusURL returns the host of the openshift monitoring prometheus route
+func (c *OcCluster) PrometheusURL(ctx context.Context) (string, error) {
+	out, err := c.oc(ctx, nil, "get", "route", "-n", "openshift-monitoring", "prometheus-k8s", "-o", "jsonpath={.spec.host}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Func PrometheusToken returns a token for the prometheus-k8s service account
+func (c *OcCluster) PrometheusToken(ctx context.Context) (string, error) {
+	out, err := c.oc(ctx, nil, "sa", "get-token", "-n", "openshift-monitoring", "prometheus-k8s")
+	if err != nil {
+		// oc sa get-token was removed in newer clients
+		out, err = c.oc(ctx, nil, "create", "token", "-n", "openshift-monitoring", "prometheus-k8s")
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Func KubeconfigSecret renders the kubeconfig secret mounted by the serving pods
+func (c *OcCluster) KubeconfigSecret(ctx context.Context) ([]byte, error) {
+	return c.oc(ctx, nil, "create", "secret", "generic", "kubeconfig", "--from-file=config="+c.Kubeconfig, "--dry-run=client", "--output=yaml")
+}
+
+// Func ListObjects returns namespace/name, or name for cluster scoped resources, of objects matching a label selector
+func (c *OcCluster) ListObjects(ctx context.Context, resource string, selector string) ([]string, error) {
+	out, err := c.oc(ctx, nil, "get", resource, "--all-namespaces", "-l", selector, "-o", `jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name}{"\n"}{end}`)
+	if err != nil {
+		return nil, err
+	}
+	var objects []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "/")
+		if line != "" {
+			objects = append(objects, line)
+		}
+	}
+	return objects, nil
+}
+
+// Func MachineConfigPools lists the machine config pools with their Updated, Updating and Degraded conditions
+func (c *OcCluster) MachineConfigPools(ctx context.Context) ([]MachineConfigPool, error) {
+	out, err := c.oc(ctx, nil, "get", "mcp", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal(out, &list)
+	if err != nil {
+		return nil, err
+	}
+	var pools []MachineConfigPool
+	for _, item := range list.Items {
+		p := MachineConfigPool{Name: item.Metadata.Name}
+		for _, cond := range item.Status.Conditions {
+			on := cond.Status == "True"
+			switch cond.Type {
+			case "Updated":
+				p.Updated = on
+			case "Updating":
+				p.Updating = on
+			case "Degraded":
+				p.Degraded = on
+			}
+		}
+		pools = append(pools, p)
+	}
+	return pools, nil
+}
+
+// Func OperatorCSVs lists the cluster service versions of a namespace with their phase
+func (c *OcCluster) OperatorCSVs(ctx context.Context, namespace string) ([]OperatorCSV, error) {
+	out, err := c.oc(ctx, nil, "get", "csv", "-n", namespace, "-o", `jsonpath={range .items[*]}{.metadata.name} {.status.phase}{"\n"}{end}`)
+	if err != nil {
+		return nil, err
+	}
+	var csvs []OperatorCSV
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		csv := OperatorCSV{Name: f[0]}
+		if len(f) > 1 {
+			csv.Phase = f[1]
+		}
+		csvs = append(csvs, csv)
+	}
+	return csvs, nil
+}
+
+// Func Namespaces lists namespaces matching a label selector, every namespace when it is empty
+func (c *OcCluster) Namespaces(ctx context.Context, selector string) ([]Namespace, error) {
+	args := []string{"get", "namespaces", "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	out, err := c.oc(ctx, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	err = json.Unmarshal(out, &list)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []Namespace
+	for _, item := range list.Items {
+		namespaces = append(namespaces, Namespace{Name: item.Metadata.Name, Labels: item.Metadata.Labels})
+	}
+	return namespaces, nil
+}
+
+// Func LabeledObjects returns objects matching a label selector, every object when it is empty, with the value of label key
+func (c *OcCluster) LabeledObjects(ctx context.Context, resource string, selector string, key string) ([]LabeledObject, error) {
+	args := []string{"get", resource, "--all-namespaces", "-o", `jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name} {.metadata.labels.` + key + `}{"\n"}{end}`}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	out, err := c.oc(ctx, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	var objects []LabeledObject
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		o := LabeledObject{Object: strings.TrimPrefix(f[0], "/")}
+		if len(f) > 1 {
+			o.Value = f[1]
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// Func DeleteObjects deletes objects given as namespace/name, or name for cluster scoped resources, without waiting
+func (c *OcCluster) DeleteObjects(ctx context.Context, resource string, objects []string) error {
+	by_namespace := make(map[string][]string)
+	var order []string
+	for _, o := range objects {
+		ns, name := "", o
+		if i := strings.Index(o, "/"); i >= 0 {
+			ns, name = o[:i], o[i+1:]
+		}
+		if _, ok := by_namespace[ns]; !ok {
+			order = append(order, ns)
+		}
+		by_namespace[ns] = append(by_namespace[ns], name)
+	}
+	for _, ns := range order {
+		args := []string{"delete", resource, "--wait=false", "--ignore-not-found"}
+		if ns != "" {
+			args = append(args, "-n", ns)
+		}
+		_, err := c.oc(ctx, nil, append(args, by_namespace[ns]...)...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Func NodePods counts the pods that are not finished on each node
+func (c *OcCluster) NodePods(ctx context.Context) (map[string]int, error) {
+	out, err := c.oc(ctx, nil, "get", "pods", "--all-namespaces", "--field-selector=status.phase!=Succeeded,status.phase!=Failed", "-o", `jsonpath={range .items[*]}{.spec.nodeName}{"\n"}{end}`)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, node := range strings.Fields(string(out)) {
+		counts[node]++
+	}
+	return counts, nil
+}
@@ -10,7 +10,8 @@ export SCALE=${SCALE:-1}
 export BFD=${BFD:-false}
 export PARENTID=${PARENTID}
 export GDOCS=${GDOCS:-false}
-uuid=${UUID}
+uuid=${UUID:-$(uuidgen)}
+serving_uuid=${SERVING_UUID:-$(uuidgen)}
 
 export vf_serving_factor=140
 num_vfs=$(( SCALE*vf_serving_factor))
@@ -91,8 +92,8 @@ token=$(oc sa get-token -n openshift-monitoring prometheus-k8s)
 
 popd
 
-echo "Lets create SPK pods.."
-kube-burner init -c workload/cfg_icni2_serving_resource_init.yml -t ${token} --uuid 1234
+echo "Lets create SPK pods..$serving_uuid"
+kube-burner init -c workload/cfg_icni2_serving_resource_init.yml -t ${token} --uuid $serving_uuid
 
 echo "Pausing for a minute.."
 sleep 60 # sleep for a minute before actual workload
@@ -102,12 +103,16 @@ echo "Lets create ICNI2 workloads..$uuid"
 kube-burner init -c ${1} -t ${token} --uuid $uuid --prometheus-url https://${prometheus_url} -m workload/metrics_full.yaml 
 
 echo "Lets generate a summary of the workloads..$uuid into Google Docs"
+  run_params="-workload ${1} -scale ${SCALE} -bfd=${BFD} -qps ${QPS} -burst ${BURST} -kube-burner-release ${KUBE_BURNER_RELEASE}"
   sudo yum -y install golang
   go build
   if [[ $GDOCS == "true" ]]; then
-    # Retrieve Servide Account yaml file
-    # Set env var GOOGLE_APPLICATION_CREDENTIALS to locationi of yaml file
-    ./web-burner.git -uuid $uuid -parent $PARENTID -gdocs=$GDOCS
+    # Set env var CREDENTIALS to a service account or oauth client secrets json file,
+    # otherwise GOOGLE_APPLICATION_CREDENTIALS or application default credentials are used
+    ./web-burner.git -uuid $uuid -parent $PARENTID -gdocs=$GDOCS ${CREDENTIALS:+-credentials $CREDENTIALS} ${run_params}
   else
-    ./web-burner.git -uuid $uuid
-  fi
\ No newline at end of file
+    ./web-burner.git -uuid $uuid ${run_params}
+  fi
+
+echo "Workload uuid..$uuid, serving init uuid..$serving_uuid"
+echo "Delete the objects of both with ./web-burner.git cleanup -uuid <uuid>"
@@ -126,8 +126,8 @@ func float_cleanup(resp string) (string, error) {
 	return out, nil
 }
 
-// Func csv_file calculates total for a summary page of the google sheet file
-func csv_file(wd string, json_files []string, uuid string, file_name string, iteration string) error {
+// Func csv_file calculates total for a summary page of the google sheet file, with the start and end times trimmed to window when not nil
+func csv_file(wd string, json_files []string, uuid string, file_name string, iteration string, meta RunMetadata, window *RunWindow) error {
 	var start_time string
 	var end_time string
 	m := make(map[string]string)
@@ -149,11 +149,14 @@ func csv_file(wd string, json_files []string, uuid string, file_name string, ite
 		j_trim := strings.TrimSpace(j)
 		j_path := filepath.Join(wd, "/collected-metrics/", j_trim)
 
-		resp1, resp2, s, e, err := summary_data(j_path)
+		resp1, resp2, s, e, err := summary_data(j_path, window)
 		if err != nil {
 			log.Println("Problem parsing json file", j, "with error", err)
 		}
-		// Make resp2 not empty to prevent break
+		// Make resp1 and resp2 not empty to prevent break
+		if len(resp1) == 0 {
+			resp1 = []string{""}
+		}
 		if len(resp2) == 0 {
 			resp2 = []string{""}
 		}
@@ -263,12 +266,27 @@ func csv_file(wd string, json_files []string, uuid string, file_name string, ite
 		if resp1[0] == "etcdLeaderChangesRate" {
 			m["EtcdLeaderChangesRate"] = resp1[1]
 		}
-		start_time = s
-		end_time = e
+		if resp1[0] == "podReadyLatencyP99" {
+			// Keep the highest p99 across all jobs
+			prev, _ := strconv.Atoi(m["PodReadyLatencyP99"])
+			cur, _ := strconv.Atoi(resp1[1])
+			if cur >= prev {
+				m["PodReadyLatencyP99"] = resp1[1]
+			}
+		}
+		// The run window spans the earliest and latest sample of all files, pod latency summaries carry no metric window
+		if s != "" {
+			start_time, end_time = widen_window(start_time, end_time, s)
+			start_time, end_time = widen_window(start_time, end_time, e)
+		}
 		i++
 	}
+	if window != nil {
+		start_time, end_time = window.trim(start_time, end_time)
+	}
 	log.Println("Finsihed unmarshalling json files and retrieving data. Attempting to write to csv file", f)
-	csv_row := [][]string{{iteration, start_time, end_time, uuid, m["MasterCPU"], m["WorkerCPU"], m["MasterMemoryActive"], m["WorkerMemoryActive"], m["MasterMemoryAvailable"], m["WorkerMemoryAvailable"], m["MasterMemoryCached"], m["WorkerMemoryCached"], m["KubeletCPU"], m["KubeletMemory"], m["CrioCPU"], m["CrioMemory"], m["API99thLatency"], m["PodStatusCount"], m["ServiceCount"], m["NamespaceCount"], m["DeploymentCount"], m["99thEtcdDiskWalFsyncDurationSeconds"], m["EtcdLeaderChangesRate"]}}
+	csv_row := [][]string{{iteration, start_time, end_time, uuid, m["MasterCPU"], m["WorkerCPU"], m["MasterMemoryActive"], m["WorkerMemoryActive"], m["MasterMemoryAvailable"], m["WorkerMemoryAvailable"], m["MasterMemoryCached"], m["WorkerMemoryCached"], m["KubeletCPU"], m["KubeletMemory"], m["CrioCPU"], m["CrioMemory"], m["API99thLatency"], m["PodStatusCount"], m["ServiceCount"], m["NamespaceCount"], m["DeploymentCount"], m["99thEtcdDiskWalFsyncDurationSeconds"], m["EtcdLeaderChangesRate"], m["PodReadyLatencyP99"]}}
+	csv_row[0] = append(csv_row[0], meta.row()...)
 	err = w.WriteAll(csv_row)
 	if err != nil {
 		return err
@@ -277,8 +295,9 @@ func csv_file(wd string, json_files []string, uuid string, file_name string, ite
 	return nil
 }
 
-// Func summary_data unmarshalls a json file into defined structs and ranges over values to determine the max value
-func summary_data(json_file string) ([]string, []string, string, string, error) {
+// Func summary_data unmarshalls a json file into defined structs and ranges over values to determine the max value, only
+// using samples within window when it is not nil
+func summary_data(json_file string, window *RunWindow) ([]string, []string, string, string, error) {
 	var empty []string
 	var empty_string string
 	var jpl []PodLatencyStruct
@@ -290,6 +309,7 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 	var start_time string
 	var end_time string
 	var max_int int
+	var samples int
 
 	json_struct_req, key := json_identifier(json_file)
 
@@ -308,6 +328,14 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if err != nil {
 			return empty, empty, empty_string, empty_string, err
 		}
+		samples = len(jpl)
+		var in []PodLatencyStruct
+		for _, v := range jpl {
+			if window.contains(v.Timestamp) {
+				in = append(in, v)
+			}
+		}
+		jpl = in
 		len := len(jpl)
 		max_int = len - 1
 	} else if json_struct_req == "json_struct_float64_instance" {
@@ -316,33 +344,94 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if err != nil {
 			return empty, empty, empty_string, empty_string, err
 		}
+		samples = len(jfi)
+		var in []JsonStructValFloatInstance
+		for _, v := range jfi {
+			if window.contains(v.Timestamp) {
+				in = append(in, v)
+			}
+		}
+		jfi = in
 		len := len(jfi)
 		max_int = len - 1
+		for _, v := range jfi {
+			start_time, end_time = widen_window(start_time, end_time, v.Timestamp)
+		}
 	} else if json_struct_req == "json_struct_float64_node" {
 		// Unmarshal data
 		err := json.Unmarshal([]byte(data), &jfn)
 		if err != nil {
 			return empty, empty, empty_string, empty_string, err
 		}
+		samples = len(jfn)
+		var in []JsonStructValFloatNode
+		for _, v := range jfn {
+			if window.contains(v.Timestamp) {
+				in = append(in, v)
+			}
+		}
+		jfn = in
 		len := len(jfn)
 		max_int = len - 1
+		for _, v := range jfn {
+			start_time, end_time = widen_window(start_time, end_time, v.Timestamp)
+		}
 	} else if json_struct_req == "json_struct_int" {
 		// Unmarshal data
 		err := json.Unmarshal([]byte(data), &jint)
 		if err != nil {
 			return empty, empty, empty_string, empty_string, err
 		}
+		samples = len(jint)
+		var in []JsonStructValInt
+		for _, v := range jint {
+			if window.contains(v.Timestamp) {
+				in = append(in, v)
+			}
+		}
+		jint = in
 		len := len(jint)
 		max_int = len - 1
+		for _, v := range jint {
+			start_time, end_time = widen_window(start_time, end_time, v.Timestamp)
+		}
 	} else {
 		err := json.Unmarshal([]byte(data), &jint)
 		if err != nil {
 			return empty, empty, empty_string, empty_string, err
 		}
+		samples = len(jint)
+		var in []JsonStructValInt
+		for _, v := range jint {
+			if window.contains(v.Timestamp) {
+				in = append(in, v)
+			}
+		}
+		jint = in
 		len := len(jint)
 		max_int = len - 1
+		for _, v := range jint {
+			start_time, end_time = widen_window(start_time, end_time, v.Timestamp)
+		}
+	}
+	// Empty files, e.g. a metric kube-burner found no samples for, have no values or window
+	if max_int < 0 {
+		if samples > 0 {
+			log.Println("Warning: json file", json_file, "has no values within the run window, skipping it")
+		} else {
+			log.Println("Warning: json file", json_file, "has no values, skipping it")
+		}
+		return empty, empty, empty_string, empty_string, nil
 	}
-	if key == "nodeCPU" {
+	if key == "pod_latency" {
+		max := 0
+		for _, v := range jpl {
+			if v.QuantileName == "Ready" && v.P99 > max {
+				max = v.P99
+			}
+		}
+		resp1 = []string{"podReadyLatencyP99", strconv.Itoa(max)}
+	} else if key == "nodeCPU" {
 		var masterCPU float64
 		var workerCPU float64
 		masterCPU = 0
@@ -361,8 +450,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				}
 			}
 		}
-		start_time = jfi[0].Timestamp
-		end_time = jfi[max_int].Timestamp
 	} else if key == "nodeMemoryActive" {
 		var masterMemoryActive int
 		var workerMemoryActive int
@@ -382,8 +469,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				}
 			}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "nodeMemoryAvailable" {
 		var masterMemoryAvailable int
 		var workerMemoryAvailable int
@@ -403,8 +488,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				}
 			}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "nodeMemoryCached" {
 		var masterMemoryCached int
 		var workerMemoryCached int
@@ -424,8 +507,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				}
 			}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "kubeletCPU" {
 		var max float64
 		max = 0
@@ -435,8 +516,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"kubeletCPU", fmt.Sprintf("%f", (v.Value))}
 			}
 		}
-		start_time = jfn[0].Timestamp
-		end_time = jfn[max_int].Timestamp
 	} else if key == "kubeletMemory" {
 		var max float64
 		max = 0
@@ -446,8 +525,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"kubeletMemory", fmt.Sprintf("%f", (v.Value))}
 			}
 		}
-		start_time = jfn[0].Timestamp
-		end_time = jfn[max_int].Timestamp
 	} else if key == "crioCPU" {
 		var max float64
 		max = 0
@@ -457,8 +534,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"crioCPU", fmt.Sprintf("%f", (v.Value))}
 			}
 		}
-		start_time = jfn[0].Timestamp
-		end_time = jfn[max_int].Timestamp
 	} else if key == "crioMemory" {
 		var max int
 		max = 0
@@ -468,8 +543,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"crioMemory", strconv.Itoa(v.Value)}
 			}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "API99thLatency" {
 		var max float64
 		max = 0
@@ -479,8 +552,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"API99thLatency", fmt.Sprintf("%f", (v.Value))}
 			}
 		}
-		start_time = jfi[0].Timestamp
-		end_time = jfi[max_int].Timestamp
 	} else if key == "podStatusCount" {
 		count := 0
 		for _, v := range jint {
@@ -492,8 +563,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if count == 0 {
 			resp1 = []string{"podStatusCount", strconv.Itoa(0)}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "serviceCount" {
 		count := 0
 		for _, v := range jint {
@@ -505,8 +574,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if count == 0 {
 			resp1 = []string{"serviceCount", strconv.Itoa(0)}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "namespaceCount" {
 		count := 0
 		for _, v := range jint {
@@ -518,8 +585,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if count == 0 {
 			resp1 = []string{"namespaceCount", strconv.Itoa(0)}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "deploymentCount" {
 		count := 0
 		for _, v := range jint {
@@ -531,8 +596,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if count == 0 {
 			resp1 = []string{"deploymentCount", strconv.Itoa(0)}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "99thEtcdDiskWalFsyncDurationSeconds" {
 		var max float64
 		max = 0
@@ -542,8 +605,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"99thEtcdDiskWalFsyncDurationSeconds", fmt.Sprintf("%f", (v.Value))}
 			}
 		}
-		start_time = jfi[0].Timestamp
-		end_time = jfi[max_int].Timestamp
 	} else if key == "etcdLeaderChangesRate" {
 		count := 0
 		for _, v := range jint {
@@ -555,8 +616,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 		if count == 0 {
 			resp1 = []string{"etcdLeaderChangesRate", strconv.Itoa(0)}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	} else if key == "default" {
 		var max int
 		max = 0
@@ -566,8 +625,6 @@ func summary_data(json_file string) ([]string, []string, string, string, error)
 				resp1 = []string{"default", strconv.Itoa(v.Value)}
 			}
 		}
-		start_time = jint[0].Timestamp
-		end_time = jint[max_int].Timestamp
 	}
 	return resp1, resp2, start_time, end_time, nil
 }
@@ -578,7 +635,7 @@ func json_identifier(json_file string) (string, string) {
 	var json_struct_req string
 	var key string
 
-	if strings.Contains(json_file, "job-podLatency") {
+	if strings.Contains(json_file, "podLatency") {
 		json_struct_req = "pod_latency_struct"
 		key = "pod_latency"
 		return json_struct_req, key
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Func capture_log collects what the test logs with the log package
+func capture_log(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestSummaryDataEmpty(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"nodeCPU-u1.json", "nodeMemoryActive-u1.json", "kubeletCPU-u1.json", "podLatency-u1.json", "serviceCount-u1.json"} {
+		t.Run(name, func(t *testing.T) {
+			buf := capture_log(t)
+			f := filepath.Join(dir, name)
+			write_file(t, f, "[]")
+
+			resp1, resp2, s, e, err := summary_data(f, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp1) != 0 || len(resp2) != 0 || s != "" || e != "" {
+				t.Errorf("got %v %v %q %q, want no values", resp1, resp2, s, e)
+			}
+			if !strings.Contains(buf.String(), "Warning: json file "+f+" has no values, skipping it") {
+				t.Errorf("missing warning in log:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestSummaryDataWindow(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "nodeCPU-u1.json")
+	write_file(t, f, `[
+		{"timestamp": "2026-10-19T09:10:00Z", "labels": {"instance": "worker-a"}, "value": 2.5},
+		{"timestamp": "2026-10-19T09:00:00.25Z", "labels": {"instance": "master-0"}, "value": 1.5},
+		{"timestamp": "2026-10-19T09:30:00Z", "labels": {"instance": "worker-b"}, "value": 3.5},
+		{"timestamp": "2026-10-19T09:20:00Z", "labels": {"instance": "master-1"}, "value": 0.5}
+	]`)
+
+	resp1, resp2, s, e, err := summary_data(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(resp1, " ") != "masterCPU 1.500000" || strings.Join(resp2, " ") != "workerCPU 3.500000" {
+		t.Errorf("got %v %v, want masterCPU 1.500000 and workerCPU 3.500000", resp1, resp2)
+	}
+	if s != "2026-10-19T09:00:00.25Z" || e != "2026-10-19T09:30:00Z" {
+		t.Errorf("got window %s - %s, want 2026-10-19T09:00:00.25Z - 2026-10-19T09:30:00Z", s, e)
+	}
+}
+
+func TestSummaryDataOutsideWindow(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "nodeCPU-u1.json")
+	write_file(t, f, `[
+		{"timestamp": "2026-10-19T08:10:00Z", "labels": {"instance": "worker-a"}, "value": 9.5},
+		{"timestamp": "2026-10-19T09:20:00Z", "labels": {"instance": "worker-a"}, "value": 2.5},
+		{"timestamp": "not a time", "labels": {"instance": "worker-b"}, "value": 7.5},
+		{"timestamp": "2026-10-19T09:30:00Z", "labels": {"instance": "master-0"}, "value": 1.5}
+	]`)
+	window := &RunWindow{Start: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), End: time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)}
+
+	resp1, resp2, s, e, err := summary_data(f, window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The serving init sample at 08:10 and the sample without a time are left out
+	if strings.Join(resp1, " ") != "masterCPU 1.500000" || strings.Join(resp2, " ") != "workerCPU 2.500000" {
+		t.Errorf("got %v %v, want masterCPU 1.500000 and workerCPU 2.500000", resp1, resp2)
+	}
+	if s != "2026-10-19T09:20:00Z" || e != "2026-10-19T09:30:00Z" {
+		t.Errorf("got window %s - %s, want 2026-10-19T09:20:00Z - 2026-10-19T09:30:00Z", s, e)
+	}
+
+	buf := capture_log(t)
+	window.End = window.Start.Add(time.Minute)
+	resp1, resp2, _, _, err = summary_data(f, window)
+	if err != nil || len(resp1) != 0 || len(resp2) != 0 {
+		t.Errorf("got %v %v, %v, want no values", resp1, resp2, err)
+	}
+	if !strings.Contains(buf.String(), "has no values within the run window") {
+		t.Errorf("missing warning in log:\n%s", buf.String())
+	}
+}
+
+// Func write_metrics writes the collected metrics of uuid u1 the csv_file tests summarize
+func write_metrics(t *testing.T, dir string) []string {
+	t.Helper()
+	metrics := map[string]string{
+		// Samples out of order, the earliest in nodeMemoryActive and the latest in nodeCPU
+		"nodeCPU-u1.json": `[
+			{"timestamp": "2026-10-19T09:40:00Z", "labels": {"instance": "worker-a"}, "value": 2},
+			{"timestamp": "2026-10-19T09:05:00Z", "labels": {"instance": "master-0"}, "value": 1},
+			{"timestamp": "2026-10-19T10:15:00Z", "labels": {"instance": "worker-b"}, "value": 4}
+		]`,
+		"nodeMemoryActive-u1.json": `[
+			{"timestamp": "2026-10-19T09:50:00Z", "labels": {"instance": "master-0"}, "value": 512},
+			{"timestamp": "2026-10-19T08:55:00Z", "labels": {"instance": "worker-a"}, "value": 256}
+		]`,
+		"kubeletCPU-u1.json": `[]`,
+		"podLatency-u1.json": `[
+			{"timestamp": "2026-10-19T11:00:00Z", "quantileName": "Ready", "p99": 1200},
+			{"timestamp": "2026-10-19T11:00:00Z", "quantileName": "Ready", "p99": 1800}
+		]`,
+	}
+	var files []string
+	for name, data := range metrics {
+		write_file(t, filepath.Join(dir, "collected-metrics", name), data)
+		files = append(files, name)
+	}
+	write_file(t, filepath.Join(dir, "gsheet", "summary.csv"), "")
+	return files
+}
+
+func TestCsvFileWindowAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := write_metrics(t, dir)
+	capture_log(t)
+
+	err := csv_file(dir, files, "u1", "summary.csv", "1", RunMetadata{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := read_csv(t, filepath.Join(dir, "gsheet", "summary.csv"))
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	row := rows[0]
+	// Pod latency summaries carry no metric window, so 11:00 is not the end
+	want := []string{"1", "2026-10-19T08:55:00Z", "2026-10-19T10:15:00Z", "u1", "1.00", "4.00", "512.00GB", "256.00GB"}
+	for i, w := range want {
+		if row[i] != w {
+			t.Errorf("column %d is %q, want %q", i, row[i], w)
+		}
+	}
+	if row[23] != "1800" {
+		t.Errorf("PodReadyLatencyP99 is %q, want 1800", row[23])
+	}
+}
+
+func TestCsvFileTrimsToWindow(t *testing.T) {
+	window := &RunWindow{
+		Start: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 10, 19, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+	// Values are columns WorkerCPU, WorkerMemoryActive and PodReadyLatencyP99, taken from the samples within the window
+	tests := []struct {
+		name   string
+		window *RunWindow
+		start  string
+		end    string
+		values []string
+	}{
+		{"no window", nil, "2026-10-19T08:55:00Z", "2026-10-19T10:15:00Z", []string{"4.00", "256.00GB", "1800"}},
+		{"workload window", window, "2026-10-19T09:05:00Z", "2026-10-19T09:50:00Z", []string{"2.00", "", ""}},
+		{"wider window", &RunWindow{Start: window.Start.Add(-time.Hour), End: window.End.Add(time.Hour)}, "2026-10-19T08:55:00Z", "2026-10-19T10:15:00Z", []string{"4.00", "256.00GB", "1800"}},
+		{"window without samples", &RunWindow{Start: window.Start.Add(-4 * time.Hour), End: window.Start.Add(-3 * time.Hour)}, "2026-10-19T05:00:00Z", "2026-10-19T06:00:00Z", []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := write_metrics(t, dir)
+			capture_log(t)
+
+			err := csv_file(dir, files, "u1", "summary.csv", "1", RunMetadata{}, tt.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			row := read_csv(t, filepath.Join(dir, "gsheet", "summary.csv"))[0]
+			if row[1] != tt.start || row[2] != tt.end {
+				t.Errorf("got window %s - %s, want %s - %s", row[1], row[2], tt.start, tt.end)
+			}
+			if values := []string{row[5], row[7], row[23]}; !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("got values %v, want %v", values, tt.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object template of the frr configmap mounted by the serving pods
+const frr_template = "objectTemplates/cm_frr.yaml"
+
+// Struct BFDTimers holds the bfd session timers of every peer in the frr config
+type BFDTimers struct {
+	DetectMultiplier int
+	ReceiveInterval  int // milliseconds
+	TransmitInterval int // milliseconds
+}
+
+// Frr defaults, also used by the serving init config when the env vars are unset
+var default_bfd_timers = BFDTimers{DetectMultiplier: 3, ReceiveInterval: 300, TransmitInterval: 300}
+
+// Func env returns the timers as the env vars read by the serving init config
+func (t BFDTimers) env() []string {
+	return []string{
+		"BFD_DETECT_MULTIPLIER=" + strconv.Itoa(t.DetectMultiplier),
+		"BFD_RECEIVE_INTERVAL=" + strconv.Itoa(t.ReceiveInterval),
+		"BFD_TRANSMIT_INTERVAL=" + strconv.Itoa(t.TransmitInterval),
+	}
+}
+
+// Func validate checks the timers are within the ranges frr accepts
+func (t BFDTimers) validate() error {
+	if t.DetectMultiplier < 2 || t.DetectMultiplier > 255 {
+		return fmt.Errorf("bfd detect-multiplier %d is outside 2-255", t.DetectMultiplier)
+	}
+	if t.ReceiveInterval < 10 || t.ReceiveInterval > 60000 {
+		return fmt.Errorf("bfd receive-interval %d is outside 10-60000 ms", t.ReceiveInterval)
+	}
+	if t.TransmitInterval < 10 || t.TransmitInterval > 60000 {
+		return fmt.Errorf("bfd transmit-interval %d is outside 10-60000 ms", t.TransmitInterval)
+	}
+	return nil
+}
+
+// Func bfd_timer_flags registers the bfd timer flags on a flag set, defaulted from default_bfd_timers
+func bfd_timer_flags(fs *flag.FlagSet, t *BFDTimers) {
+	*t = default_bfd_timers
+	fs.IntVar(&t.DetectMultiplier, "bfd-detect-multiplier", t.DetectMultiplier, "missed bfd packets before a peer is down")
+	fs.IntVar(&t.ReceiveInterval, "bfd-receive-interval", t.ReceiveInterval, "bfd receive interval in milliseconds")
+	fs.IntVar(&t.TransmitInterval, "bfd-transmit-interval", t.TransmitInterval, "bfd transmit interval in milliseconds")
+}
+
+// Func frr_configmap renders the frr configmap of a serving namespace with the served peers the ip plan gives that namespace
+func frr_configmap(t *template.Template, plan *IPPlan, timers BFDTimers, iteration int) ([]byte, error) {
+	if iteration < 1 || iteration > len(plan.Gateways) {
+		return nil, fmt.Errorf("ip plan does not cover serving-ns-%d", iteration)
+	}
+	// The serving init config passes the shared peers or those of every namespace, the template picks those of its iteration
+	var peers []interface{}
+	for _, ns_peers := range plan.Peers {
+		var list []interface{}
+		for _, p := range ns_peers {
+			list = append(list, p)
+		}
+		peers = append(peers, list)
+	}
+	job := WorkloadJob{Name: "create-cms-job", Namespace: "serving-ns", NamespacedIterations: true}
+	o := WorkloadObject{
+		ObjectTemplate: frr_template,
+		Replicas:       1,
+		InputVars: map[string]interface{}{
+			"peers":            peers,
+			"detectMultiplier": timers.DetectMultiplier,
+			"receiveInterval":  timers.ReceiveInterval,
+			"transmitInterval": timers.TransmitInterval,
+		},
+	}
+	obj, err := render_object(t, job, o, iteration, 1, "frr")
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the namespace kube-burner would create the configmap in so the file can be applied directly
+	var cm map[string]interface{}
+	err = yaml.Unmarshal(obj.Data, &cm)
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := cm["metadata"].(map[string]interface{})
+	if meta == nil {
+		return nil, fmt.Errorf("%s has no metadata", frr_template)
+	}
+	meta["namespace"] = job_namespace(job, iteration)
+	return yaml.Marshal(cm)
+}
+
+// Func frr_cmd writes the frr configmap of every serving namespace from the ip plan and bfd timers
+func frr_cmd(args []string) error {
+	fs := flag.NewFlagSet("frr", flag.ExitOnError)
+	var ip_cfg IPPlanConfig
+	ip_plan_flags(fs, &ip_cfg)
+	var timers BFDTimers
+	bfd_timer_flags(fs, &timers)
+	scale := fs.Int("scale", env_int("SCALE", 1), "scale factor of the workload, 35 serving namespaces per unit")
+	namespace := fs.Int("namespace", 0, "only print the configmap of serving-ns-<n> to stdout")
+	out := fs.String("out", "frr", "directory the configmaps are written to")
+	fs.Parse(args)
+
+	err := timers.validate()
+	if err != nil {
+		return err
+	}
+	iterations := 35 * *scale
+	plan, err := new_ip_plan(ip_cfg, iterations, serving_replicas)
+	if err != nil {
+		return err
+	}
+	data, err := bundle_file(frr_template)
+	if err != nil {
+		return err
+	}
+	t, err := template.New(filepath.Base(frr_template)).Funcs(kube_burner_funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	if *namespace > 0 {
+		if *namespace > iterations {
+			return fmt.Errorf("serving-ns-%d is not created at scale %d", *namespace, *scale)
+		}
+		cm, err := frr_configmap(t, plan, timers, *namespace)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(cm)
+		return err
+	}
+
+	err = os.MkdirAll(*out, 0755)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= iterations; i++ {
+		cm, err := frr_configmap(t, plan, timers, i)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(*out, fmt.Sprintf("frr-serving-ns-%d.yml", i)), cm, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	log_fields("wrote frr configmaps", "dir", *out, "namespaces", iterations, "peers", ip_cfg.Peers)
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// Peer lines of a rendered frr configmap
+var frr_peer = regexp.MustCompile(`peer (\S+) interface net1`)
+
+func TestFrrConfigmapPeersPerNamespace(t *testing.T) {
+	cfg := default_ip_plan
+	cfg.Peers = 3
+	cfg.PerNamespace = true
+	plan, err := new_ip_plan(cfg, 2, serving_replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bundle_file(frr_template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := template.New(filepath.Base(frr_template)).Funcs(kube_burner_funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timers := BFDTimers{DetectMultiplier: 5, ReceiveInterval: 100, TransmitInterval: 200}
+	want := map[int][]string{
+		1: {"192.168.216.2", "192.168.216.3", "192.168.216.4"},
+		2: {"192.168.216.5", "192.168.216.6", "192.168.216.7"},
+	}
+	for iteration, peers := range want {
+		cm, err := frr_configmap(tmpl, plan, timers, iteration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range frr_peer.FindAllStringSubmatch(string(cm), -1) {
+			got = append(got, m[1])
+		}
+		if strings.Join(got, ",") != strings.Join(peers, ",") {
+			t.Errorf("serving-ns-%d peers %v, want %v", iteration, got, peers)
+		}
+		for _, s := range []string{fmt.Sprintf("namespace: serving-ns-%d", iteration), "detect-multiplier 5", "receive-interval 100", "transmit-interval 200"} {
+			if !strings.Contains(string(cm), s) {
+				t.Errorf("serving-ns-%d configmap has no %q:\n%s", iteration, s, cm)
+			}
+		}
+	}
+
+	if _, err := frr_configmap(tmpl, plan, timers, 3); err == nil {
+		t.Error("rendered serving-ns-3 which the plan does not cover")
+	}
+}
+
+func TestServingInitFrrPeers(t *testing.T) {
+	// The serving init config passes the plan as env vars, each configmap keeps only the peers of its namespace
+	cfg := default_ip_plan
+	cfg.Peers = 2
+	cfg.PerNamespace = true
+	plan, err := new_ip_plan(cfg, 35, serving_replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := render_workload(RenderOptions{Workload: "workload/cfg_icni2_serving_resource_init.yml", Scale: 1, QPS: 20, Burst: 20, IPPlan: plan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, o := range r.Objects {
+		if o.Template != frr_template {
+			continue
+		}
+		found++
+		var got []string
+		for _, m := range frr_peer.FindAllStringSubmatch(string(o.Data), -1) {
+			got = append(got, m[1])
+		}
+		iteration, err := strconv.Atoi(strings.TrimPrefix(o.Namespace, "serving-ns-"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, ",") != strings.Join(plan.Peers[iteration-1], ",") {
+			t.Errorf("%s peers %v, want %v", o.Namespace, got, plan.Peers[iteration-1])
+		}
+	}
+	if found != 35 {
+		t.Errorf("rendered %d frr configmaps, want 35", found)
+	}
+}
+
+// Func frr_configmaps returns the peers of every frr configmap the serving init config renders
+func frr_configmaps(t *testing.T, plan *IPPlan) map[string][]string {
+	t.Helper()
+	r, err := render_workload(RenderOptions{Workload: "workload/cfg_icni2_serving_resource_init.yml", Scale: 1, QPS: 20, Burst: 20, IPPlan: plan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cms := make(map[string][]string)
+	for _, o := range r.Objects {
+		if o.Template == frr_template {
+			cms[o.Namespace] = frr_peer.FindAllString(string(o.Data), -1)
+		}
+	}
+	return cms
+}
+
+func TestDefaultPlanFrrMatchesFallback(t *testing.T) {
+	// Every serving namespace runs bfd with the same 118 served peers with or without the default plan
+	plan, err := new_ip_plan(default_ip_plan, 35, serving_replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	with, without := frr_configmaps(t, plan), frr_configmaps(t, nil)
+	if len(with) != 35 || !reflect.DeepEqual(with, without) {
+		t.Fatalf("%d configmaps with the default plan differ from the %d without", len(with), len(without))
+	}
+	var want []string
+	for i := 2; i <= 119; i++ {
+		want = append(want, fmt.Sprintf("peer 192.168.216.%d interface net1", i))
+	}
+	for ns, peers := range with {
+		if !reflect.DeepEqual(peers, want) {
+			t.Errorf("%s peers %v, want 192.168.216.2-119", ns, peers)
+		}
+	}
+
+	data, err := bundle_file(frr_template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := template.New(filepath.Base(frr_template)).Funcs(kube_burner_funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iteration := range []int{1, 17, 35} {
+		got, err := frr_configmap(tmpl, plan, default_bfd_timers, iteration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fallback, err := frr_configmap(tmpl, &IPPlan{Gateways: plan.Gateways}, default_bfd_timers, iteration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(fallback) {
+			t.Errorf("serving-ns-%d configmap with the default plan\n%s\ndiffers from the fallback\n%s", iteration, got, fallback)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Struct RoleTemplate is the object template used for a role and whether it takes the namespace index as inputVar ns
+type RoleTemplate struct {
+	Template string
+	NsVar    bool
+}
+
+// Object templates of each role per icni mode, the f5 variants route through the bigip and need the namespace index
+var role_templates = map[string]map[string]RoleTemplate{
+	"icni2": {
+		"configmap":          {Template: "objectTemplates/cluster_density_configmap.yml"},
+		"secret":             {Template: "objectTemplates/cluster_density_secret.yml"},
+		"served-pod":         {Template: "objectTemplates/node_density_pod_served.yml"},
+		"pod-service":        {Template: "objectTemplates/node_density_pod_service.yml"},
+		"served-deployment":  {Template: "objectTemplates/cluster_density_dep_served.yml"},
+		"deployment-service": {Template: "objectTemplates/cluster_density_dep_service.yml"},
+		"app-endpoint":       {Template: "objectTemplates/cluster_density_dep_served_ports.yml"},
+		"app-service":        {Template: "objectTemplates/cluster_density_pod_service_ports.yml"},
+	},
+	"icni1-f5": {
+		"configmap":          {Template: "objectTemplates/cluster_density_configmap.yml"},
+		"secret":             {Template: "objectTemplates/cluster_density_secret.yml"},
+		"served-pod":         {Template: "objectTemplates/node_density_pod_served_f5.yml", NsVar: true},
+		"pod-service":        {Template: "objectTemplates/node_density_pod_service_f5.yml", NsVar: true},
+		"served-deployment":  {Template: "objectTemplates/cluster_density_dep_served_f5.yml", NsVar: true},
+		"deployment-service": {Template: "objectTemplates/cluster_density_pod_service_f5.yml", NsVar: true},
+		"app-endpoint":       {Template: "objectTemplates/cluster_density_dep_served_ports.yml"},
+		"app-service":        {Template: "objectTemplates/cluster_density_pod_service_ports.yml"},
+	},
+	"regular": {
+		"configmap":          {Template: "objectTemplates/cluster_density_configmap.yml"},
+		"secret":             {Template: "objectTemplates/cluster_density_secret.yml"},
+		"served-pod":         {Template: "objectTemplates/node_density_pod_served.yml"},
+		"pod-service":        {Template: "objectTemplates/node_density_pod_service.yml"},
+		"served-deployment":  {Template: "objectTemplates/cluster_density_dep_served.yml"},
+		"deployment-service": {Template: "objectTemplates/cluster_density_dep_service.yml"},
+		"app-endpoint":       {Template: "objectTemplates/cluster_density_pod_served.yml"},
+		"app-service":        {Template: "objectTemplates/cluster_density_pod_service_ports.yml"},
+	},
+}
+
+// Struct TopologySpec is a compact description of a workload that generate expands into kube-burner jobs
+type TopologySpec struct {
+	ICNI       string          `yaml:"icni"`    // default icni mode: icni2, icni1-f5 or regular
+	Indexer    bool            `yaml:"indexer"` // enable the elastic indexer
+	Namespaces []NamespaceSpec `yaml:"namespaces"`
+	Apps       []AppSpec       `yaml:"apps"`
+}
+
+// Struct NamespaceSpec is a group of namespaces, each created by its own job with the same object mix
+type NamespaceSpec struct {
+	Name     string      `yaml:"name"`     // namespace prefix, namespaces are <name>-<index>
+	Job      string      `yaml:"job"`      // job name prefix, jobs are <job>-<index>
+	PerScale int         `yaml:"perScale"` // namespaces per SCALE
+	ICNI     string      `yaml:"icni"`
+	Objects  []ObjectMix `yaml:"objects"`
+}
+
+// Struct ObjectMix is an object of a namespace, given by role or by template
+type ObjectMix struct {
+	Role     string `yaml:"role"`
+	Template string `yaml:"template"`
+	NsVar    bool   `yaml:"nsVar"`
+	ICNI     string `yaml:"icni"`
+	Replicas int    `yaml:"replicas"`
+}
+
+// Struct AppSpec is a service endpoint distribution created in namespaced iterations of a namespace group
+type AppSpec struct {
+	Namespace string         `yaml:"namespace"`
+	Job       string         `yaml:"job"`      // job name prefix, jobs are <job>-<n>
+	PerScale  int            `yaml:"perScale"` // jobIterations per SCALE
+	ICNI      string         `yaml:"icni"`
+	Services  []ServiceGroup `yaml:"services"`
+}
+
+// Struct ServiceGroup is count services for each endpoint size, generated as one job
+type ServiceGroup struct {
+	Endpoints []int `yaml:"endpoints"`
+	Count     int   `yaml:"count"`
+}
+
+// Struct GenObject is an object entry of a generated job
+type GenObject struct {
+	Template string
+	Replicas int
+	NsVar    bool
+	Group    string // inputVar ns prefix of app objects, the service index is appended
+}
+
+// Struct GenJob is a generated job, either ranged over namespaces or with namespaced iterations
+type GenJob struct {
+	Name      string
+	Namespace string
+	PerScale  int
+	Ranged    bool // one job per namespace index
+	Repeat    int  // number of services for each endpoint size of app jobs
+	Objects   []GenObject
+}
+
+// Template of the generated kube-burner config, [[ ]] delimiters keep the kube-burner {{ }} expressions as text
+var generate_template = template.Must(template.New("generate").Delims("[[", "]]").Parse(`---
+# Generated by web-burner generate, edit the topology spec instead
+global:
+  writeToFile: true
+  metricsDirectory: collected-metrics
+  measurements:
+    - name: podLatency
+      esIndex: kube-burner
+  indexerConfig:
+    enabled: [[ .Indexer ]]
+    esServers: [https://search-perfscale-dev-chmf5l4sh66lvxbnadi4bznl3a.us-west-2.es.amazonaws.com]
+    insecureSkipVerify: true
+    defaultIndex: kube-burner
+    type: elastic
+
+jobs:
+[[- range .Jobs ]]
+[[- if .Ranged ]]
+{{ range $index, $val := sequence 1 (multiply [[ .PerScale ]] .SCALE) }}
+  - name: [[ .Name ]]-{{ $val }}
+    jobType: create
+    jobIterations: 1
+    qps: {{ $.QPS }}
+    burst: {{ $.BURST }}
+    namespacedIterations: false
+    cleanup: false
+    namespace: [[ .Namespace ]]-{{ $val }}
+    podWait: false
+    waitWhenFinished: true
+    waitFor: ["Pod"]
+    verifyObjects: true
+    errorOnVerify: false
+    jobIterationDelay: 0s
+    jobPause: 0s
+    objects:
+[[- range .Objects ]]
+      - objectTemplate: [[ .Template ]]
+        replicas: [[ .Replicas ]]
+[[- if .NsVar ]]
+        inputVars:
+          ns: {{ $val }}
+[[- end ]]
+[[- end ]]
+{{ end }}
+[[- else ]]
+
+  - name: [[ .Name ]]
+    jobType: create
+    jobIterations: {{ multiply [[ .PerScale ]] .SCALE }}
+    qps: {{ .QPS }}
+    burst: {{ .BURST }}
+    namespacedIterations: true
+    cleanup: false
+    namespace: [[ .Namespace ]]
+    podWait: false
+    waitWhenFinished: true
+    waitFor: ["Pod"]
+    verifyObjects: true
+    errorOnVerify: false
+    jobIterationDelay: 0s
+    jobPause: 0s
+    objects:
+{{ range $index, $val := sequence 1 [[ .Repeat ]] }}
+[[- range .Objects ]]
+      - objectTemplate: [[ .Template ]]
+        replicas: [[ .Replicas ]]
+        inputVars:
+          ns: [[ .Group ]]-{{ $val }}
+[[- end ]]
+{{ end }}
+[[- end ]]
+[[- end ]]
+`))
+
+// Func resolve_role returns the template of an object by its role in the icni mode, or its explicit template
+func resolve_role(icni string, o ObjectMix) (RoleTemplate, error) {
+	if o.Template != "" {
+		return RoleTemplate{Template: o.Template, NsVar: o.NsVar}, nil
+	}
+	if o.ICNI != "" {
+		icni = o.ICNI
+	}
+	roles, ok := role_templates[icni]
+	if !ok {
+		return RoleTemplate{}, fmt.Errorf("unknown icni mode %q, use icni2, icni1-f5 or regular", icni)
+	}
+	t, ok := roles[o.Role]
+	if !ok {
+		return RoleTemplate{}, fmt.Errorf("unknown role %q for icni mode %s", o.Role, icni)
+	}
+	return t, nil
+}
+
+// Func group_name returns the inputVar ns prefix of the nth service group, group-a, group-b, ... then group-aa
+func group_name(n int) string {
+	name := ""
+	for n >= 0 {
+		name = string(rune('a'+n%26)) + name
+		n = n/26 - 1
+	}
+	return "group-" + name
+}
+
+// Func generate_jobs expands a topology spec into the jobs of a kube-burner config
+func generate_jobs(spec TopologySpec) ([]GenJob, error) {
+	var jobs []GenJob
+	for _, ns := range spec.Namespaces {
+		icni := spec.ICNI
+		if ns.ICNI != "" {
+			icni = ns.ICNI
+		}
+		job := GenJob{Name: ns.Job, Namespace: ns.Name, PerScale: ns.PerScale, Ranged: true}
+		if job.Name == "" {
+			job.Name = "job-" + ns.Name
+		}
+		for _, o := range ns.Objects {
+			t, err := resolve_role(icni, o)
+			if err != nil {
+				return nil, fmt.Errorf("namespace %s: %v", ns.Name, err)
+			}
+			job.Objects = append(job.Objects, GenObject{Template: t.Template, Replicas: o.Replicas, NsVar: t.NsVar})
+		}
+		jobs = append(jobs, job)
+	}
+
+	for _, app := range spec.Apps {
+		icni := spec.ICNI
+		if app.ICNI != "" {
+			icni = app.ICNI
+		}
+		endpoint, err := resolve_role(icni, ObjectMix{Role: "app-endpoint"})
+		if err != nil {
+			return nil, fmt.Errorf("apps in %s: %v", app.Namespace, err)
+		}
+		service, err := resolve_role(icni, ObjectMix{Role: "app-service"})
+		if err != nil {
+			return nil, fmt.Errorf("apps in %s: %v", app.Namespace, err)
+		}
+		prefix := app.Job
+		if prefix == "" {
+			prefix = "app-job"
+		}
+		// Group letters run across the service groups so every service of an iteration has its own selector
+		group := 0
+		for i, sg := range app.Services {
+			job := GenJob{Name: fmt.Sprintf("%s-%d", prefix, i+1), Namespace: app.Namespace, PerScale: app.PerScale, Repeat: sg.Count}
+			if job.Repeat == 0 {
+				job.Repeat = 1
+			}
+			for _, e := range sg.Endpoints {
+				g := group_name(group)
+				group++
+				job.Objects = append(job.Objects,
+					GenObject{Template: endpoint.Template, Replicas: e, Group: g},
+					GenObject{Template: service.Template, Replicas: 1, Group: g},
+				)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Func generate_workload renders the kube-burner config of a topology spec
+func generate_workload(spec TopologySpec) ([]byte, error) {
+	jobs, err := generate_jobs(spec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = generate_template.Execute(&buf, struct {
+		Indexer bool
+		Jobs    []GenJob
+	}{spec.Indexer, jobs})
+	return buf.Bytes(), err
+}
+
+// Func load_topology reads a topology spec
+func load_topology(path string) (TopologySpec, error) {
+	spec := TopologySpec{ICNI: "icni2"}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	err = yaml.Unmarshal(data, &spec)
+	if err != nil {
+		return spec, fmt.Errorf("unable to parse topology spec %s: %v", path, err)
+	}
+	return spec, nil
+}
+
+// Func generate_cmd writes the kube-burner workload config generated from a topology spec
+func generate_cmd(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	topology := fs.String("topology", "", "topology spec describing namespaces, object mix and service endpoints")
+	out := fs.String("out", "", "workload config written, default is stdout")
+	fs.Parse(args)
+
+	if *topology == "" {
+		return fmt.Errorf("please provide the topology spec using flag '-topology'")
+	}
+	spec, err := load_topology(*topology)
+	if err != nil {
+		return err
+	}
+	cfg, err := generate_workload(spec)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(cfg)
+		return err
+	}
+	err = os.WriteFile(*out, cfg, 0644)
+	if err != nil {
+		return err
+	}
+	log_fields("wrote workload config", "topology", *topology, "file", *out)
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"math/bits"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Struct IPPlanConfig holds the ranges serving gateway and served peer addresses are allocated from
+type IPPlanConfig struct {
+	Network      string   // subnet of the sr-iov network, its prefix length is used for the pod addresses
+	ServingCIDR  []string // one cidr per serving replica, or a single cidr split between the replicas
+	PeerCIDR     string   // cidr of the served peers the serving pods run bfd with
+	PeerOffset   int      // host index of the first peer
+	Peers        int      // served peers each serving namespace runs bfd with
+	PerNamespace bool     // give every serving namespace its own peers instead of one list shared by all
+}
+
+// Serving pods per serving namespace created by the serving init config
+const serving_replicas = 4
+
+// Defaults matching the addresses pod_serving.yml and cm_frr.yaml use without a plan, every serving namespace runs bfd with 192.168.216.2-119
+var default_ip_plan = IPPlanConfig{
+	Network:     "192.168.216.0/21",
+	ServingCIDR: []string{"192.168.219.0/24", "192.168.220.0/24", "192.168.221.0/24", "192.168.222.0/24"},
+	PeerCIDR:    "192.168.216.0/24",
+	PeerOffset:  2,
+	Peers:       118,
+}
+
+// Struct IPPlan is the address of every serving replica and served peer
+type IPPlan struct {
+	PrefixLen int
+	Gateways  [][]string // gateway address per serving namespace iteration, then per replica
+	Peers     [][]string // served peer addresses shared by every serving namespace, or one list per iteration
+}
+
+// Func namespace_peers returns the served peers of a serving namespace iteration
+func (p *IPPlan) namespace_peers(iteration int) []string {
+	if len(p.Peers) == 1 {
+		return p.Peers[0]
+	}
+	return p.Peers[iteration-1]
+}
+
+// Func ip_to_int converts an ipv4 address to an integer
+func ip_to_int(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+// Func int_to_ip converts an integer to an ipv4 address
+func int_to_ip(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+// Struct ipv4_range is the first and last address of a cidr
+type ipv4_range struct {
+	cidr  string
+	first uint32
+	last  uint32
+}
+
+// Func parse_range parses an ipv4 cidr into its address range
+func parse_range(cidr string) (ipv4_range, error) {
+	_, n, err := net.ParseCIDR(strings.TrimSpace(cidr))
+	if err != nil {
+		return ipv4_range{}, err
+	}
+	if n.IP.To4() == nil {
+		return ipv4_range{}, fmt.Errorf("%s is not an ipv4 cidr", cidr)
+	}
+	ones, size := n.Mask.Size()
+	first := ip_to_int(n.IP)
+	return ipv4_range{cidr: n.String(), first: first, last: first + uint32(1<<uint(size-ones)) - 1}, nil
+}
+
+// Func contains checks if a range lies within another
+func (r ipv4_range) contains(o ipv4_range) bool {
+	return o.first >= r.first && o.last <= r.last
+}
+
+// Func overlaps checks if two ranges share an address
+func (r ipv4_range) overlaps(o ipv4_range) bool {
+	return r.first <= o.last && o.first <= r.last
+}
+
+// Func split_range divides a range into the smallest power of two of equal subnets holding n parts
+func split_range(r ipv4_range, n int) ([]ipv4_range, error) {
+	if n <= 1 {
+		return []ipv4_range{r}, nil
+	}
+	k := bits.Len(uint(n - 1))
+	size := uint64(r.last-r.first) + 1
+	if size>>uint(k) < 4 {
+		return nil, fmt.Errorf("%s is too small to split between %d replicas", r.cidr, n)
+	}
+	step := uint32(size >> uint(k))
+	var parts []ipv4_range
+	for i := 0; i < n; i++ {
+		first := r.first + uint32(i)*step
+		parts = append(parts, ipv4_range{cidr: fmt.Sprintf("%s/%d", int_to_ip(first), 32-bits.Len32(step-1)), first: first, last: first + step - 1})
+	}
+	return parts, nil
+}
+
+// Func new_ip_plan allocates gateways for iterations serving namespaces with replicas pods each, and the served peers
+func new_ip_plan(cfg IPPlanConfig, iterations int, replicas int) (*IPPlan, error) {
+	network, err := parse_range(cfg.Network)
+	if err != nil {
+		return nil, fmt.Errorf("network: %v", err)
+	}
+	_, n, _ := net.ParseCIDR(cfg.Network)
+	prefix, _ := n.Mask.Size()
+
+	var serving []ipv4_range
+	for _, c := range cfg.ServingCIDR {
+		r, err := parse_range(c)
+		if err != nil {
+			return nil, fmt.Errorf("serving cidr: %v", err)
+		}
+		serving = append(serving, r)
+	}
+	if len(serving) == 1 {
+		serving, err = split_range(serving[0], replicas)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(serving) < replicas {
+		return nil, fmt.Errorf("%d serving cidrs given for %d replicas", len(serving), replicas)
+	}
+	peers, err := parse_range(cfg.PeerCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("peer cidr: %v", err)
+	}
+
+	// Ranges must lie in the sr-iov network and not share addresses
+	ranges := append(append([]ipv4_range{}, serving[:replicas]...), peers)
+	names := make([]string, len(ranges))
+	for i, r := range ranges {
+		names[i] = fmt.Sprintf("serving cidr %s of replica %d", r.cidr, i+1)
+	}
+	names[len(ranges)-1] = "peer cidr " + peers.cidr
+	for i, r := range ranges {
+		if !network.contains(r) {
+			return nil, fmt.Errorf("%s is outside the network %s", names[i], network.cidr)
+		}
+		for j := i + 1; j < len(ranges); j++ {
+			if r.overlaps(ranges[j]) {
+				return nil, fmt.Errorf("%s overlaps %s", names[i], names[j])
+			}
+		}
+	}
+
+	plan := &IPPlan{PrefixLen: prefix}
+	used := make(map[uint32]string)
+	assign := func(addr uint32, r ipv4_range, who string) (string, error) {
+		// Skip the network and broadcast addresses of the sr-iov network
+		if addr > r.last || addr == network.first || addr == network.last {
+			return "", fmt.Errorf("%s overflows %s", who, r.cidr)
+		}
+		if prev, ok := used[addr]; ok {
+			return "", fmt.Errorf("%s and %s both use %s", who, prev, int_to_ip(addr))
+		}
+		used[addr] = who
+		return int_to_ip(addr).String(), nil
+	}
+
+	for i := 1; i <= iterations; i++ {
+		var gws []string
+		for rep := 1; rep <= replicas; rep++ {
+			r := serving[rep-1]
+			gw, err := assign(r.first+uint32(i), r, fmt.Sprintf("serving-ns-%d replica %d", i, rep))
+			if err != nil {
+				return nil, fmt.Errorf("%v, %d serving namespaces need %d addresses per replica cidr", err, iterations, iterations+1)
+			}
+			gws = append(gws, gw)
+		}
+		plan.Gateways = append(plan.Gateways, gws)
+	}
+	// Serving namespaces share one list of peers, or get their own in consecutive blocks
+	next := peers.first + uint32(cfg.PeerOffset)
+	lists := 1
+	if cfg.PerNamespace {
+		lists = iterations
+	}
+	for i := 1; i <= lists; i++ {
+		var ns_peers []string
+		for p := 1; p <= cfg.Peers; p++ {
+			who := fmt.Sprintf("peer %d", p)
+			if cfg.PerNamespace {
+				who = fmt.Sprintf("served-ns-%d peer %d", i, p)
+			}
+			peer, err := assign(next, peers, who)
+			if err != nil && cfg.PerNamespace {
+				return nil, fmt.Errorf("%v, %d serving namespaces with %d peers each need %d addresses from offset %d", err, iterations, cfg.Peers, iterations*cfg.Peers, cfg.PeerOffset)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%v, %d shared peers need host indexes %d-%d of the peer cidr", err, cfg.Peers, cfg.PeerOffset, cfg.PeerOffset+cfg.Peers-1)
+			}
+			ns_peers = append(ns_peers, peer)
+			next++
+		}
+		plan.Peers = append(plan.Peers, ns_peers)
+	}
+	return plan, nil
+}
+
+// Func env returns the plan as yaml flow values read by the serving init config
+func (p *IPPlan) env() []string {
+	return []string{
+		"IP_PLAN_GATEWAYS=" + flow_list(p.Gateways),
+		"IP_PLAN_PREFIX=" + strconv.Itoa(p.PrefixLen),
+		"IP_PLAN_PEERS=" + flow_list(p.Peers),
+	}
+}
+
+// Func flow_list formats addresses per iteration as a yaml flow list of lists
+func flow_list(values [][]string) string {
+	var lists []string
+	for _, v := range values {
+		lists = append(lists, "["+strings.Join(v, ",")+"]")
+	}
+	return "[" + strings.Join(lists, ",") + "]"
+}
+
+// Func ip_plan_flags registers the ip plan flags on a flag set, defaulted from default_ip_plan
+func ip_plan_flags(fs *flag.FlagSet, cfg *IPPlanConfig) {
+	*cfg = default_ip_plan
+	fs.StringVar(&cfg.Network, "network", cfg.Network, "subnet of the sr-iov network the serving pods attach to")
+	fs.Func("serving-cidrs", "comma separated cidr per serving replica, or one cidr split between replicas (default "+strings.Join(cfg.ServingCIDR, ",")+")", func(s string) error {
+		cfg.ServingCIDR = strings.Split(s, ",")
+		return nil
+	})
+	fs.StringVar(&cfg.PeerCIDR, "peer-cidr", cfg.PeerCIDR, "cidr of the served bfd peers")
+	fs.IntVar(&cfg.PeerOffset, "peer-offset", cfg.PeerOffset, "host index of the first bfd peer in the peer cidr")
+	fs.IntVar(&cfg.Peers, "peers", cfg.Peers, "bfd peers of each serving namespace")
+	fs.BoolVar(&cfg.PerNamespace, "peers-per-namespace", cfg.PerNamespace, "give every serving namespace its own bfd peers instead of sharing them")
+}
+
+// Func peer_range formats consecutive peers as their first and last address
+func peer_range(peers []string) string {
+	if len(peers) <= 2 {
+		return strings.Join(peers, ", ")
+	}
+	return fmt.Sprintf("%s - %s (%d)", peers[0], peers[len(peers)-1], len(peers))
+}
+
+// Func ipplan_cmd allocates the serving and peer addresses for a scale and reports overflows and overlaps
+func ipplan_cmd(args []string) error {
+	fs := flag.NewFlagSet("ipplan", flag.ExitOnError)
+	var cfg IPPlanConfig
+	ip_plan_flags(fs, &cfg)
+	scale := fs.Int("scale", env_int("SCALE", 1), "scale factor of the workload, 35 serving namespaces per unit")
+	replicas := fs.Int("replicas", serving_replicas, "serving pods per serving namespace")
+	all := fs.Bool("all", false, "print every address instead of the first and last serving namespaces")
+	fs.Parse(args)
+
+	iterations := 35 * *scale
+	plan, err := new_ip_plan(cfg, iterations, *replicas)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAMESPACE\tGATEWAYS (/%d)\tPEERS\n", plan.PrefixLen)
+	for i, gws := range plan.Gateways {
+		if !*all && i >= 2 && i < len(plan.Gateways)-2 {
+			if i == 2 {
+				fmt.Fprintln(w, "...\t\t")
+			}
+			continue
+		}
+		fmt.Fprintf(w, "serving-ns-%d\t%s\t%s\n", i+1, strings.Join(gws, ", "), peer_range(plan.namespace_peers(i+1)))
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	log_fields("ip plan has no overflow or overlap", "serving_namespaces", iterations, "replicas", *replicas, "peers", cfg.Peers, "per_namespace", cfg.PerNamespace)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewIPPlan(t *testing.T) {
+	single := default_ip_plan
+	single.ServingCIDR = []string{"192.168.220.0/22"}
+	overlap := default_ip_plan
+	overlap.ServingCIDR = []string{"192.168.219.0/24", "192.168.220.0/24", "192.168.220.128/25", "192.168.222.0/24"}
+	peer_overlap := default_ip_plan
+	peer_overlap.PeerCIDR = "192.168.219.0/24"
+	outside := default_ip_plan
+	outside.ServingCIDR = []string{"192.168.219.0/24", "192.168.220.0/24", "192.168.221.0/24", "192.168.224.0/24"}
+	too_few := default_ip_plan
+	too_few.ServingCIDR = []string{"192.168.219.0/24", "192.168.220.0/24"}
+	many_peers := default_ip_plan
+	many_peers.Peers = 8
+	many_peers.PerNamespace = true
+	shared_overflow := default_ip_plan
+	shared_overflow.Peers = 255
+
+	tests := []struct {
+		name  string
+		cfg   IPPlanConfig
+		scale int
+		err   string
+		first []string
+		last  []string
+	}{
+		{name: "scale 1", cfg: default_ip_plan, scale: 1,
+			first: []string{"192.168.219.1", "192.168.220.1", "192.168.221.1", "192.168.222.1"},
+			last:  []string{"192.168.219.35", "192.168.220.35", "192.168.221.35", "192.168.222.35"}},
+		{name: "scale 7 fits the default cidrs", cfg: default_ip_plan, scale: 7,
+			last: []string{"192.168.219.245", "192.168.220.245", "192.168.221.245", "192.168.222.245"}},
+		{name: "scale 8 overflows", cfg: default_ip_plan, scale: 8, err: "serving-ns-256 replica 1 overflows 192.168.219.0/24"},
+		{name: "single cidr split between replicas", cfg: single, scale: 1,
+			first: []string{"192.168.220.1", "192.168.221.1", "192.168.222.1", "192.168.223.1"}},
+		{name: "single cidr last replica stops before the broadcast address", cfg: single, scale: 8, err: "serving-ns-255 replica 4 overflows 192.168.223.0/24"},
+		{name: "overlapping serving cidrs", cfg: overlap, scale: 1, err: "serving cidr 192.168.220.0/24 of replica 2 overlaps serving cidr 192.168.220.128/25 of replica 3"},
+		{name: "serving cidr overlaps the peers", cfg: peer_overlap, scale: 1, err: "serving cidr 192.168.219.0/24 of replica 1 overlaps peer cidr 192.168.219.0/24"},
+		{name: "cidr outside the network", cfg: outside, scale: 1, err: "serving cidr 192.168.224.0/24 of replica 4 is outside the network 192.168.216.0/21"},
+		{name: "fewer cidrs than replicas", cfg: too_few, scale: 1, err: "2 serving cidrs given for 4 replicas"},
+		{name: "peers overflow their cidr", cfg: many_peers, scale: 1, err: "served-ns-32 peer 7 overflows 192.168.216.0/24"},
+		{name: "shared peers overflow their cidr", cfg: shared_overflow, scale: 1, err: "peer 255 overflows 192.168.216.0/24, 255 shared peers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := new_ip_plan(tt.cfg, 35*tt.scale, serving_replicas)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Gateways) != 35*tt.scale {
+				t.Fatalf("got %d serving namespaces, want %d", len(plan.Gateways), 35*tt.scale)
+			}
+			if tt.first != nil && strings.Join(plan.Gateways[0], ",") != strings.Join(tt.first, ",") {
+				t.Errorf("serving-ns-1 gateways %v, want %v", plan.Gateways[0], tt.first)
+			}
+			if tt.last != nil && strings.Join(plan.Gateways[len(plan.Gateways)-1], ",") != strings.Join(tt.last, ",") {
+				t.Errorf("last gateways %v, want %v", plan.Gateways[len(plan.Gateways)-1], tt.last)
+			}
+			if plan.PrefixLen != 21 {
+				t.Errorf("got prefix /%d, want /21", plan.PrefixLen)
+			}
+		})
+	}
+}
+
+func TestServingTemplateFallbackOverflow(t *testing.T) {
+	// Without an ip plan the templates fall back to 192.168.(218+replica).(iteration) which ends at serving-ns-255
+	for _, tt := range []struct {
+		scale int
+		err   string
+	}{
+		{7, ""},
+		{8, "serving-ns-256 overflows the default gateways 192.168.219.0/24"},
+	} {
+		_, err := render_workload(RenderOptions{Workload: "workload/cfg_icni2_serving_resource_init.yml", Scale: tt.scale, QPS: 20, Burst: 20})
+		if tt.err == "" && err != nil {
+			t.Errorf("scale %d: %v", tt.scale, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("scale %d: got error %v, want %q", tt.scale, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cristoper/gsheet/gsheets"
+)
+
+// Struct JsonStructTiming holds the fields of a metric document that place it in a job
+type JsonStructTiming struct {
+	Timestamp string `json:"timestamp"`
+	JobName   string `json:"jobName"`
+}
+
+// Struct JobTiming is when a kube-burner job ran
+type JobTiming struct {
+	Job    string
+	Start  time.Time
+	End    time.Time
+	Source string // log when kube-burner logged the job, metrics when only metric timestamps were found
+}
+
+// Func duration returns how long the job ran
+func (t JobTiming) duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// Job start and end lines of kube-burner logs, with the time field of the line
+var (
+	kube_burner_log_time   = regexp.MustCompile(`time="([^"]+)"`)
+	kube_burner_job_start  = regexp.MustCompile(`Triggering job: ([^\s"]+)`)
+	kube_burner_log_layout = []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02T15:04:05"}
+)
+
+// Func parse_log_time parses the time field of a kube-burner log line, logged in local time without a zone
+func parse_log_time(line string) (time.Time, bool) {
+	m := kube_burner_log_time.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, false
+	}
+	for _, layout := range kube_burner_log_layout {
+		t, err := time.ParseInLocation(layout, m[1], time.Local)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Func metric_job_timings returns the first and last metric timestamp of each job in the metric files of uuid
+func metric_job_timings(wd string, uuid string) (map[string]*JobTiming, error) {
+	timings := make(map[string]*JobTiming)
+	entries, err := os.ReadDir(filepath.Join(wd, "collected-metrics"))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !strings.Contains(e.Name(), uuid) || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(wd, "collected-metrics", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var docs []JsonStructTiming
+		err = json.Unmarshal(data, &docs)
+		if err != nil {
+			log.Println("Problem parsing json file", e.Name(), "with error", err)
+			continue
+		}
+		for _, d := range docs {
+			ts, err := time.Parse(time.RFC3339Nano, d.Timestamp)
+			if err != nil || d.JobName == "" {
+				continue
+			}
+			t, ok := timings[d.JobName]
+			if !ok {
+				timings[d.JobName] = &JobTiming{Job: d.JobName, Start: ts, End: ts, Source: "metrics"}
+				continue
+			}
+			if ts.Before(t.Start) {
+				t.Start = ts
+			}
+			if ts.After(t.End) {
+				t.End = ts
+			}
+		}
+	}
+	return timings, nil
+}
+
+// Func log_job_timings returns the start and end of each job logged in the kube-burner logs of a run
+func log_job_timings(wd string, uuid string) (map[string]*JobTiming, error) {
+	timings := make(map[string]*JobTiming)
+	logs, err := filepath.Glob(filepath.Join(wd, runs_dir, uuid, "kube-burner-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		f, err := os.Open(l)
+		if err != nil {
+			return nil, err
+		}
+		s := bufio.NewScanner(f)
+		s.Buffer(make([]byte, 64*1024), 1024*1024)
+		for s.Scan() {
+			line := s.Text()
+			ts, ok := parse_log_time(line)
+			if !ok {
+				continue
+			}
+			if m := kube_burner_job_start.FindStringSubmatch(line); m != nil {
+				timings[m[1]] = &JobTiming{Job: m[1], Start: ts, Source: "log"}
+			}
+			if m := kube_burner_job_took.FindStringSubmatch(line); m != nil {
+				t, ok := timings[m[1]]
+				if !ok {
+					// The start line was not logged, take it from the reported duration
+					seconds, _ := strconv.ParseFloat(m[2], 64)
+					t = &JobTiming{Job: m[1], Start: ts.Add(-time.Duration(seconds * float64(time.Second))), Source: "log"}
+					timings[m[1]] = t
+				}
+				t.End = ts
+			}
+		}
+		f.Close()
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return timings, nil
+}
+
+// Func job_timings merges the job times of the kube-burner logs and the metric timestamps, preferring the logs, ordered by start
+func job_timings(wd string, uuid string) ([]JobTiming, error) {
+	merged, err := metric_job_timings(wd, uuid)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if merged == nil {
+		merged = make(map[string]*JobTiming)
+	}
+	logged, err := log_job_timings(wd, uuid)
+	if err != nil {
+		return nil, err
+	}
+	for job, t := range logged {
+		// Jobs still running or killed have no end in the log
+		if t.End.IsZero() {
+			if m, ok := merged[job]; ok {
+				t.End = m.End
+			} else {
+				continue
+			}
+		}
+		merged[job] = t
+	}
+
+	var timings []JobTiming
+	for _, t := range merged {
+		timings = append(timings, *t)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Start.Equal(timings[j].Start) {
+			return timings[i].Job < timings[j].Job
+		}
+		return timings[i].Start.Before(timings[j].Start)
+	})
+	return timings, nil
+}
+
+// Header of the per job csv written next to the summary
+var job_timing_header = []string{"Job", "StartTime", "EndTime", "DurationSeconds", "Source"}
+
+// Func job_timing_rows formats job times as csv rows
+func job_timing_rows(timings []JobTiming) [][]string {
+	var rows [][]string
+	for _, t := range timings {
+		rows = append(rows, []string{t.Job, t.Start.UTC().Format(time.RFC3339), t.End.UTC().Format(time.RFC3339), strconv.FormatFloat(t.duration().Seconds(), 'f', 0, 64), t.Source})
+	}
+	return rows
+}
+
+// Func job_timing_csv writes the job times of uuid to gsheet/jobs-<uuid>.csv
+func job_timing_csv(wd string, uuid string) error {
+	timings, err := job_timings(wd, uuid)
+	if err != nil {
+		return err
+	}
+	if len(timings) == 0 {
+		log.Println("No job times found in collected-metrics or kube-burner logs for uuid", uuid)
+		return nil
+	}
+	f := filepath.Join(wd, "gsheet", "jobs-"+uuid+".csv")
+	file, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	err = w.WriteAll(append([][]string{job_timing_header}, job_timing_rows(timings)...))
+	if err != nil {
+		return err
+	}
+	log.Println("Wrote times of", len(timings), "jobs to", f)
+	return nil
+}
+
+// Tab of the google sheet holding the job times of every run
+const job_timing_tab = "Jobs"
+
+// Func merge_job_rows replaces the rows of uuid in the Jobs tab values with the job times of the local csv, keyed by a UUID column
+func merge_job_rows(existing [][]string, uuid string, rows [][]string) [][]string {
+	merged := [][]string{append([]string{"UUID"}, job_timing_header...)}
+	for i, r := range existing {
+		if i == 0 || len(r) == 0 || r[0] == uuid {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	for i, r := range rows {
+		if i == 0 {
+			continue
+		}
+		merged = append(merged, append([]string{uuid}, r...))
+	}
+	return merged
+}
+
+// Func upload_job_timings writes the job times of uuid to the Jobs tab of the google sheet, creating the tab on first use
+func upload_job_timings(wd string, uuid string, sheet_id string, gsheet_svc *gsheets.Service) error {
+	f := filepath.Join(wd, "gsheet", "jobs-"+uuid+".csv")
+	r, err := os.Open(f)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	id, err := gsheet_svc.SheetFromTitle(sheet_id, job_timing_tab)
+	if err != nil {
+		return err
+	}
+	var existing [][]string
+	if id == nil {
+		log.Println("Adding tab", job_timing_tab, "to google sheet id", sheet_id)
+		err = gsheet_svc.NewSheet(sheet_id, job_timing_tab)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Rows of other runs are kept, a summary run again for the same uuid replaces its rows
+		values, err := gsheet_svc.GetRangeRaw(sheet_id, job_timing_tab)
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			var row []string
+			for _, c := range v {
+				row = append(row, fmt.Sprint(c))
+			}
+			existing = append(existing, row)
+		}
+		err = gsheet_svc.Clear(sheet_id, job_timing_tab)
+		if err != nil {
+			return err
+		}
+	}
+
+	merged := merge_job_rows(existing, uuid, rows)
+	values := make([][]interface{}, len(merged))
+	for i, row := range merged {
+		values[i] = make([]interface{}, len(row))
+		for c, v := range row {
+			values[i][c] = v
+			if i == 0 || merged[0][c] != "DurationSeconds" {
+				continue
+			}
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				values[i][c] = n
+			}
+		}
+	}
+	log.Println("Writing times of", len(rows)-1, "jobs to tab", job_timing_tab, "of google sheet id", sheet_id)
+	_, err = gsheet_svc.UpdateRangeRaw(sheet_id, job_timing_tab+"!A1", values)
+	return err
+}
+
+// Func jobs_cmd prints the start, end and duration of each kube-burner job of a run
+func jobs_cmd(args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ExitOnError)
+	id := fs.String("uuid", os.Getenv("UUID"), "uuid of the run")
+	fs.Parse(args)
+	if *id == "" {
+		return fmt.Errorf("please provide the uuid of the run using flag '-uuid'")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	timings, err := job_timings(wd, *id)
+	if err != nil {
+		return err
+	}
+	if len(timings) == 0 {
+		return fmt.Errorf("no job times found in collected-metrics or %s for uuid %s", filepath.Join(runs_dir, *id), *id)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTART\tEND\tDURATION\tSOURCE")
+	for _, t := range timings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Job, t.Start.UTC().Format(time.RFC3339), t.End.UTC().Format(time.RFC3339), t.duration().Round(time.Second), t.Source)
+	}
+	return w.Flush()
+}
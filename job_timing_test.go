@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Func write_file writes data to a file under the working directory, creating its directory
+func write_file(t *testing.T, f string, data string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(f), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(f, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Func in_zone runs the test with time.Local set to a zone away from UTC, like a lab host
+func in_zone(t *testing.T) {
+	t.Helper()
+	local := time.Local
+	time.Local = time.FixedZone("CEST", 2*60*60)
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestParseLogTime(t *testing.T) {
+	in_zone(t)
+	tests := []struct {
+		line string
+		want string
+		ok   bool
+	}{
+		// kube-burner logs the local time without a zone
+		{`time="2026-10-19 10:00:00" level=info msg="Triggering job: job-2-1"`, "2026-10-19T08:00:00Z", true},
+		{`time="2026-10-19T10:00:00" level=info`, "2026-10-19T08:00:00Z", true},
+		{`time="2026-10-19T10:00:00.5Z" level=info`, "2026-10-19T10:00:00.5Z", true},
+		{`time="2026-10-19T10:00:00+05:00" level=info`, "2026-10-19T05:00:00Z", true},
+		{`time="yesterday" level=info`, "", false},
+		{`level=info msg="no time"`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parse_log_time(tt.line)
+		if ok != tt.ok {
+			t.Errorf("%s: parsed %t, want %t", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && got.UTC().Format(time.RFC3339Nano) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.line, got.UTC().Format(time.RFC3339Nano), tt.want)
+		}
+	}
+}
+
+func TestLogJobTimings(t *testing.T) {
+	in_zone(t)
+	dir := t.TempDir()
+	write_file(t, filepath.Join(dir, runs_dir, "u1", "kube-burner-serving-init.log"), `time="2026-10-19 10:00:00" level=info msg="Triggering job: init-job"
+time="2026-10-19 10:00:30" level=info msg="Job init-job took 30.00 seconds"
+time="2026-10-19 10:01:00" level=info msg="Job create-networks-job took 20 seconds"
+`)
+	write_file(t, filepath.Join(dir, runs_dir, "u1", "kube-burner-workload.log"), `time="2026-10-19 11:00:00" level=info msg="Triggering job: job-2-1"
+time="2026-10-19 11:05:00" level=error msg="Job job-2-1 failed"
+`)
+	write_file(t, filepath.Join(dir, runs_dir, "u2", "kube-burner-workload.log"), `time="2026-10-19 12:00:00" level=info msg="Triggering job: other"
+`)
+
+	timings, err := log_job_timings(dir, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]string{
+		"init-job": {"2026-10-19T08:00:00Z", "2026-10-19T08:00:30Z"},
+		// No start line, the start is the end minus the logged duration
+		"create-networks-job": {"2026-10-19T08:00:40Z", "2026-10-19T08:01:00Z"},
+		// Still running when the log ends
+		"job-2-1": {"2026-10-19T09:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+	if len(timings) != len(want) {
+		t.Fatalf("got %d jobs, want %d: %v", len(timings), len(want), timings)
+	}
+	for job, w := range want {
+		got, ok := timings[job]
+		if !ok {
+			t.Errorf("job %s not found", job)
+			continue
+		}
+		if got.Start.UTC().Format(time.RFC3339) != w[0] || got.End.UTC().Format(time.RFC3339) != w[1] || got.Source != "log" {
+			t.Errorf("job %s is %s - %s from %s, want %s - %s from log", job, got.Start.UTC(), got.End.UTC(), got.Source, w[0], w[1])
+		}
+	}
+}
+
+func TestMetricJobTimings(t *testing.T) {
+	dir := t.TempDir()
+	write_file(t, filepath.Join(dir, "collected-metrics", "nodeCPU-u1.json"), `[
+		{"timestamp": "2026-10-19T09:10:00Z", "jobName": "job-2-1", "value": 1},
+		{"timestamp": "2026-10-19T09:00:00Z", "jobName": "job-2-1", "value": 1},
+		{"timestamp": "2026-10-19T09:20:00Z", "jobName": "app-job-1", "value": 1},
+		{"timestamp": "not a time", "jobName": "app-job-1", "value": 1},
+		{"timestamp": "2026-10-19T08:00:00Z", "value": 1}
+	]`)
+	write_file(t, filepath.Join(dir, "collected-metrics", "podLatency-u1.json"), `[
+		{"timestamp": "2026-10-19T09:30:00.5Z", "jobName": "job-2-1"},
+		{"timestamp": "2026-10-19T09:15:00Z", "jobName": "app-job-1"}
+	]`)
+	write_file(t, filepath.Join(dir, "collected-metrics", "broken-u1.json"), `{not json`)
+	write_file(t, filepath.Join(dir, "collected-metrics", "nodeCPU-u2.json"), `[{"timestamp": "2026-10-19T07:00:00Z", "jobName": "job-2-1"}]`)
+
+	timings, err := metric_job_timings(dir, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]string{
+		"job-2-1":   {"2026-10-19T09:00:00Z", "2026-10-19T09:30:00.5Z"},
+		"app-job-1": {"2026-10-19T09:15:00Z", "2026-10-19T09:20:00Z"},
+	}
+	if len(timings) != len(want) {
+		t.Fatalf("got %d jobs, want %d: %v", len(timings), len(want), timings)
+	}
+	for job, w := range want {
+		got := timings[job]
+		if got == nil || got.Start.Format(time.RFC3339Nano) != w[0] || got.End.Format(time.RFC3339Nano) != w[1] || got.Source != "metrics" {
+			t.Errorf("job %s is %+v, want %s - %s from metrics", job, got, w[0], w[1])
+		}
+	}
+}
+
+func TestJobTimingsMerge(t *testing.T) {
+	in_zone(t)
+	dir := t.TempDir()
+	write_file(t, filepath.Join(dir, "collected-metrics", "nodeCPU-u1.json"), `[
+		{"timestamp": "2026-10-19T08:59:00Z", "jobName": "job-2-1"},
+		{"timestamp": "2026-10-19T09:20:00Z", "jobName": "job-2-1"},
+		{"timestamp": "2026-10-19T08:00:10Z", "jobName": "init-job"},
+		{"timestamp": "2026-10-19T08:00:20Z", "jobName": "init-job"},
+		{"timestamp": "2026-10-19T09:30:00Z", "jobName": "app-job-1"},
+		{"timestamp": "2026-10-19T09:40:00Z", "jobName": "app-job-1"}
+	]`)
+	write_file(t, filepath.Join(dir, runs_dir, "u1", "kube-burner-workload.log"), `time="2026-10-19 10:00:00" level=info msg="Triggering job: init-job"
+time="2026-10-19 10:00:30" level=info msg="Job init-job took 30.00 seconds"
+time="2026-10-19 11:00:00" level=info msg="Triggering job: job-2-1"
+time="2026-10-19 11:50:00" level=info msg="Triggering job: killed-job"
+`)
+
+	timings, err := job_timings(dir, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, tt := range timings {
+		got = append(got, strings.Join([]string{tt.Job, tt.Start.UTC().Format(time.RFC3339), tt.End.UTC().Format(time.RFC3339), tt.Source}, " "))
+	}
+	want := []string{
+		// The log is preferred over metric timestamps
+		"init-job 2026-10-19T08:00:00Z 2026-10-19T08:00:30Z log",
+		// A logged job without an end takes the end of its metrics
+		"job-2-1 2026-10-19T09:00:00Z 2026-10-19T09:20:00Z log",
+		// A job only in metrics, while killed-job has neither an end nor metrics and is left out
+		"app-job-1 2026-10-19T09:30:00Z 2026-10-19T09:40:00Z metrics",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	rows := job_timing_rows(timings)
+	if strings.Join(rows[0], ",") != "init-job,2026-10-19T08:00:00Z,2026-10-19T08:00:30Z,30,log" {
+		t.Errorf("csv row %v", rows[0])
+	}
+}
+
+func TestMergeJobRows(t *testing.T) {
+	header := append([]string{"UUID"}, job_timing_header...)
+	existing := [][]string{
+		header,
+		{"u0", "job-2-1", "2026-10-18T09:00:00Z", "2026-10-18T09:20:00Z", "1200", "log"},
+		{"u1", "job-2-1", "old", "old", "1", "log"},
+	}
+	rows := [][]string{job_timing_header, {"job-2-1", "2026-10-19T09:00:00Z", "2026-10-19T09:20:00Z", "1200", "log"}}
+
+	merged := merge_job_rows(existing, "u1", rows)
+	want := [][]string{
+		header,
+		{"u0", "job-2-1", "2026-10-18T09:00:00Z", "2026-10-18T09:20:00Z", "1200", "log"},
+		{"u1", "job-2-1", "2026-10-19T09:00:00Z", "2026-10-19T09:20:00Z", "1200", "log"},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("got %v, want %v", merged, want)
+	}
+	for i := range want {
+		if strings.Join(merged[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d is %v, want %v", i, merged[i], want[i])
+		}
+	}
+	if got := merge_job_rows(nil, "u1", rows); len(got) != 2 || got[0][0] != "UUID" {
+		t.Errorf("new tab rows %v", got)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release tarball of a kube-burner version, the same one create_icni2_workload.sh downloads
+const kube_burner_url = "https://github.com/cloud-bulldozer/kube-burner/releases/download/v%s/kube-burner-%s-Linux-x86_64.tar.gz"
+
+// Release used when KUBE_BURNER_RELEASE is unset, the default of create_icni2_workload.sh
+const default_kube_burner_release = "0.14.1"
+
+// SHA-256 of the release tarballs, a download is only cached when it matches. Add a release with the digest from its
+// release page, or pass -kube-burner-sha256 for a release not listed here
+var kube_burner_sha256 = map[string]string{}
+
+// Struct KubeBurnerOptions holds the arguments of a kube-burner init invocation
+type KubeBurnerOptions struct {
+	Config         string
+	UUID           string
+	Token          string
+	PrometheusURL  string
+	MetricsProfile string
+	AlertProfile   string
+	LogLevel       string        // kube-burner default when empty
+	Timeout        time.Duration // kube-burner default when zero
+	SkipTLSVerify  *bool         // kube-burner default when nil
+	Env            []string      // extra KEY=value pairs, e.g. SCALE and BFD read by the workload templates
+	LogFile        string        // file the output is copied to, only streamed when empty
+}
+
+// Func args builds the kube-burner init command line
+func (o KubeBurnerOptions) args() []string {
+	args := []string{"init", "-c", o.Config, "--uuid", o.UUID}
+	if o.Token != "" {
+		args = append(args, "-t", o.Token)
+	}
+	if o.PrometheusURL != "" {
+		args = append(args, "--prometheus-url", o.PrometheusURL)
+	}
+	if o.MetricsProfile != "" {
+		args = append(args, "-m", o.MetricsProfile)
+	}
+	if o.AlertProfile != "" {
+		args = append(args, "-a", o.AlertProfile)
+	}
+	if o.LogLevel != "" {
+		args = append(args, "--log-level", o.LogLevel)
+	}
+	if o.Timeout > 0 {
+		args = append(args, "--timeout", o.Timeout.String())
+	}
+	if o.SkipTLSVerify != nil {
+		args = append(args, "--skip-tls-verify="+strconv.FormatBool(*o.SkipTLSVerify))
+	}
+	return args
+}
+
+// Struct KubeBurnerJob is a job summary logged by kube-burner
+type KubeBurnerJob struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Struct KubeBurnerResult is the outcome of a kube-burner invocation parsed from its exit status and logs
+type KubeBurnerResult struct {
+	Config   string          `json:"config"`
+	UUID     string          `json:"uuid"`
+	Start    time.Time       `json:"start"`
+	End      time.Time       `json:"end"`
+	ExitCode int             `json:"exitCode"`
+	Jobs     []KubeBurnerJob `json:"jobs,omitempty"`
+	Errors   []string        `json:"errors,omitempty"` // messages of error and fatal log lines
+	LogFile  string          `json:"logFile,omitempty"`
+}
+
+// Interface KubeBurner runs kube-burner jobs so fakes can replace the binary in tests
+type KubeBurner interface {
+	Init(ctx context.Context, opts KubeBurnerOptions) (KubeBurnerResult, error)
+	Version(ctx context.Context) (string, error)
+}
+
+// Struct ExecKubeBurner runs a kube-burner binary pinned to Release from CacheDir, or the one found in PATH when Release is empty
+type ExecKubeBurner struct {
+	Release  string
+	CacheDir string // default is kube-burner under the user cache dir
+	SHA256   string // digest of the release tarball, the one pinned in kube_burner_sha256 when empty
+}
+
+// Func kube_burner_flags registers the kube-burner release, cache and digest flags on a flag set, defaulted from the env
+func kube_burner_flags(fs *flag.FlagSet, b *ExecKubeBurner) {
+	*b = ExecKubeBurner{Release: env_default("KUBE_BURNER_RELEASE", default_kube_burner_release), CacheDir: os.Getenv("KUBE_BURNER_CACHE"), SHA256: os.Getenv("KUBE_BURNER_SHA256")}
+	fs.StringVar(&b.Release, "kube-burner-release", b.Release, "kube-burner release, downloaded into the cache when missing")
+	fs.StringVar(&b.CacheDir, "kube-burner-cache", b.CacheDir, "directory of cached kube-burner releases, default is web-burner/kube-burner in the user cache dir")
+	fs.StringVar(&b.SHA256, "kube-burner-sha256", b.SHA256, "sha256 of the kube-burner release tarball, needed to download a release without a pinned digest")
+}
+
+// Func cache_dir returns the directory holding the cached kube-burner releases
+func (b ExecKubeBurner) cache_dir() (string, error) {
+	if b.CacheDir != "" {
+		return b.CacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "web-burner", "kube-burner"), nil
+}
+
+// Func cached returns the path of the pinned release in the cache
+func (b ExecKubeBurner) cached() (string, error) {
+	dir, err := b.cache_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strings.TrimPrefix(b.Release, "v"), "kube-burner"), nil
+}
+
+// Func locate finds the kube-burner binary without downloading: the cached release, or PATH when its version matches
+func (b ExecKubeBurner) locate(ctx context.Context) (string, error) {
+	path, err := exec.LookPath("kube-burner")
+	if b.Release == "" {
+		if err != nil {
+			return "", fmt.Errorf("kube-burner not found in PATH: %v", err)
+		}
+		return path, nil
+	}
+	bin, cerr := b.cached()
+	if cerr != nil {
+		return "", cerr
+	}
+	if _, serr := os.Stat(bin); serr == nil {
+		return bin, nil
+	}
+	if err == nil {
+		version, verr := binary_version(ctx, path)
+		if verr == nil && strings.TrimPrefix(version, "v") == strings.TrimPrefix(b.Release, "v") {
+			return path, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// Func binary locates the pinned kube-burner, downloading the release into the cache when missing
+func (b ExecKubeBurner) binary(ctx context.Context) (string, error) {
+	bin, err := b.locate(ctx)
+	if err == nil || !os.IsNotExist(err) {
+		return bin, err
+	}
+	bin, err = b.cached()
+	if err != nil {
+		return "", err
+	}
+	release := strings.TrimPrefix(b.Release, "v")
+	url := fmt.Sprintf(kube_burner_url, release, release)
+	sum := b.SHA256
+	if sum == "" {
+		sum = kube_burner_sha256[release]
+	}
+	if sum == "" {
+		return "", fmt.Errorf("unable to provision kube-burner %s: no sha256 pinned for the release, pass the digest of %s with flag 'kube-burner-sha256'", release, url)
+	}
+	log_fields("downloading kube-burner", "release", release, "url", url, "dir", filepath.Dir(bin))
+	err = download_kube_burner(ctx, url, bin, sum)
+	if err != nil {
+		return "", fmt.Errorf("unable to provision kube-burner %s: %v", release, err)
+	}
+	return bin, nil
+}
+
+// Func download_kube_burner extracts the kube-burner binary of a release tarball to dst once the tarball matches sum
+func download_kube_burner(ctx context.Context, url string, dst string, sum string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	err = os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	// Write next to the destination and rename once verified, so an interrupted or tampered download is not cached
+	// and concurrent downloads do not share a file
+	f, err := os.CreateTemp(filepath.Dir(dst), ".kube-burner-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = extract_kube_burner(resp.Body, f, url, sum)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0755)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Func extract_kube_burner copies the kube-burner binary of a release tarball to f and checks the tarball digest
+func extract_kube_burner(body io.Reader, f io.Writer, url string, sum string) error {
+	hash := sha256.New()
+	tarball := io.TeeReader(body, hash)
+	gz, err := gzip.NewReader(tarball)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s has no kube-burner binary", url)
+		}
+		if err != nil {
+			return err
+		}
+		if filepath.Base(h.Name) == "kube-burner" && h.Typeflag == tar.TypeReg {
+			break
+		}
+	}
+	_, err = io.Copy(f, tr)
+	if err != nil {
+		return err
+	}
+	// The digest covers the whole tarball, including what follows the binary
+	_, err = io.Copy(io.Discard, tarball)
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, strings.TrimSpace(sum)) {
+		return fmt.Errorf("%s has sha256 %s, want %s", url, got, sum)
+	}
+	return nil
+}
+
+// Job summary and log level of kube-burner log lines
+var (
+	kube_burner_job_took = regexp.MustCompile(`Job (\S+) took ([\d.]+) seconds`)
+	kube_burner_level    = regexp.MustCompile(`level=(error|fatal) msg="((?:[^"\\]|\\.)*)"`)
+)
+
+// Struct log_parser collects job summaries and errors from kube-burner output as it is written
+type log_parser struct {
+	partial []byte
+	result  *KubeBurnerResult
+}
+
+// Func Write splits the output into lines and parses each complete one
+func (p *log_parser) Write(data []byte) (int, error) {
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		p.line(string(p.partial[:i]))
+		p.partial = p.partial[i+1:]
+	}
+}
+
+// Func line records a job summary or error from one log line
+func (p *log_parser) line(line string) {
+	if m := kube_burner_job_took.FindStringSubmatch(line); m != nil {
+		seconds, _ := strconv.ParseFloat(m[2], 64)
+		p.result.Jobs = append(p.result.Jobs, KubeBurnerJob{Name: m[1], Seconds: seconds})
+	}
+	if m := kube_burner_level.FindStringSubmatch(line); m != nil {
+		msg, err := strconv.Unquote(`"` + m[2] + `"`)
+		if err != nil {
+			msg = m[2]
+		}
+		p.result.Errors = append(p.result.Errors, msg)
+	}
+}
+
+// Func Init runs kube-burner init, streaming its output and copying it to opts.LogFile
+func (b ExecKubeBurner) Init(ctx context.Context, opts KubeBurnerOptions) (KubeBurnerResult, error) {
+	result := KubeBurnerResult{Config: opts.Config, UUID: opts.UUID, LogFile: opts.LogFile, Start: time.Now()}
+	bin, err := b.binary(ctx)
+	if err != nil {
+		return result, err
+	}
+	parser := &log_parser{result: &result}
+	out := io.MultiWriter(os.Stdout, parser)
+	if opts.LogFile != "" {
+		err = os.MkdirAll(filepath.Dir(opts.LogFile), 0755)
+		if err != nil {
+			return result, err
+		}
+		f, err := os.OpenFile(opts.LogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return result, err
+		}
+		defer f.Close()
+		out = io.MultiWriter(os.Stdout, f, parser)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, opts.args()...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	log_fields("running kube-burner", "binary", bin, "config", opts.Config, "uuid", opts.UUID, "log", opts.LogFile)
+	err = cmd.Run()
+	parser.line(string(parser.partial))
+	result.End = time.Now()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exit.ExitCode()
+			return result, fmt.Errorf("kube-burner exited with status %d%s", result.ExitCode, result.error_hint())
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// Func error_hint returns the last logged error and the log file to look at
+func (r KubeBurnerResult) error_hint() string {
+	hint := ""
+	if len(r.Errors) > 0 {
+		hint = ": " + r.Errors[len(r.Errors)-1]
+	}
+	if r.LogFile != "" {
+		hint += ", see " + r.LogFile
+	}
+	return hint
+}
+
+// Func Version returns the version of the pinned kube-burner, without downloading it
+func (b ExecKubeBurner) Version(ctx context.Context) (string, error) {
+	bin, err := b.locate(ctx)
+	if err != nil {
+		return "", err
+	}
+	return binary_version(ctx, bin)
+}
+
+// Func binary_version runs kube-burner version on a binary
+func binary_version(ctx context.Context, bin string) (string, error) {
+	out, err := exec.CommandContext(ctx, bin, "version").Output()
+	if err != nil {
+		return "", err
+	}
+	return parse_kube_burner_version(string(out)), nil
+}
+
+// Func parse_kube_burner_version finds the version line in kube-burner version output
+func parse_kube_burner_version(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return strings.TrimSpace(out)
+}
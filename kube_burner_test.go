@@ -0,0 +1,157 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKubeBurnerOptionsArgs(t *testing.T) {
+	skip := false
+	tests := []struct {
+		name string
+		opts KubeBurnerOptions
+		want string
+	}{
+		{"defaults", KubeBurnerOptions{Config: "cfg.yml", UUID: "u1"}, "init -c cfg.yml --uuid u1"},
+		{"prometheus", KubeBurnerOptions{Config: "cfg.yml", UUID: "u1", Token: "tok", PrometheusURL: "https://prom", MetricsProfile: "metrics.yaml"},
+			"init -c cfg.yml --uuid u1 -t tok --prometheus-url https://prom -m metrics.yaml"},
+		{"all", KubeBurnerOptions{Config: "cfg.yml", UUID: "u1", AlertProfile: "alerts.yaml", LogLevel: "debug", Timeout: 90 * time.Minute, SkipTLSVerify: &skip},
+			"init -c cfg.yml --uuid u1 -a alerts.yaml --log-level debug --timeout 1h30m0s --skip-tls-verify=false"},
+		// Env and the log file are not arguments
+		{"env", KubeBurnerOptions{Config: "cfg.yml", UUID: "u1", Env: []string{"SCALE=2"}, LogFile: "run.log"}, "init -c cfg.yml --uuid u1"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.opts.args(), " "); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLogParser(t *testing.T) {
+	var result KubeBurnerResult
+	p := &log_parser{result: &result}
+	log := `time="2026-10-19 10:00:00" level=info msg="Triggering job: job-2-1"
+time="2026-10-19 10:01:00" level=info msg="Job job-2-1 took 60.50 seconds"
+time="2026-10-19 10:01:01" level=error msg="Error creating object: \"served-ns-1\" exists"
+time="2026-10-19 10:02:00" level=info msg="Job app-job-1 took 59 seconds"
+time="2026-10-19 10:02:01" level=fatal msg="timeout waiting for pods"`
+	// Chunks split lines anywhere, and the last line has no newline until the process exits
+	for i := 0; i < len(log); i += 7 {
+		end := i + 7
+		if end > len(log) {
+			end = len(log)
+		}
+		n, err := p.Write([]byte(log[i:end]))
+		if err != nil || n != end-i {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("parsed errors %q before the last line was complete", result.Errors)
+	}
+	p.line(string(p.partial))
+
+	want_jobs := []KubeBurnerJob{{"job-2-1", 60.5}, {"app-job-1", 59}}
+	if len(result.Jobs) != len(want_jobs) {
+		t.Fatalf("got jobs %+v, want %+v", result.Jobs, want_jobs)
+	}
+	for i, j := range want_jobs {
+		if result.Jobs[i] != j {
+			t.Errorf("job %d is %+v, want %+v", i, result.Jobs[i], j)
+		}
+	}
+	want_errors := []string{`Error creating object: "served-ns-1" exists`, "timeout waiting for pods"}
+	if strings.Join(result.Errors, "|") != strings.Join(want_errors, "|") {
+		t.Errorf("got errors %q, want %q", result.Errors, want_errors)
+	}
+	if hint := result.error_hint(); hint != ": timeout waiting for pods" {
+		t.Errorf("error hint %q", hint)
+	}
+}
+
+// Func release_tarball builds a kube-burner release tarball holding a binary with content
+func release_tarball(t *testing.T, content string) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct{ name, data string }{{"LICENSE", "license"}, {"kube-burner", content}, {"README.md", "readme"}} {
+		err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0755, Size: int64(len(f.data)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.data))
+	}
+	tw.Close()
+	gz.Close()
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func TestDownloadKubeBurner(t *testing.T) {
+	tarball, sum := release_tarball(t, "#!/bin/sh\necho kube-burner\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		sum  string
+		err  string
+	}{
+		{"matching digest", sum, ""},
+		{"matching digest in upper case", strings.ToUpper(sum), ""},
+		{"other digest", strings.Repeat("0", 64), "has sha256 " + sum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dst := filepath.Join(dir, "0.14.1", "kube-burner")
+			err := download_kube_burner(context.Background(), srv.URL, dst, tt.sum)
+			files, _ := filepath.Glob(filepath.Join(dir, "0.14.1", "*"))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				if len(files) != 0 {
+					t.Errorf("failed download left %v", files)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(dst)
+			if err != nil || string(data) != "#!/bin/sh\necho kube-burner\n" {
+				t.Errorf("extracted %q, %v", data, err)
+			}
+			if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("extracted binary mode %v, %v", info.Mode(), err)
+			}
+			if len(files) != 1 {
+				t.Errorf("download left %v", files)
+			}
+		})
+	}
+}
+
+func TestKubeBurnerBinaryNeedsDigest(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	b := ExecKubeBurner{Release: "0.0.1-unpinned", CacheDir: t.TempDir()}
+	_, err := b.binary(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no sha256 pinned") {
+		t.Fatalf("got error %v, want the download refused without a digest", err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Struct LabelChange is a node label set by a run with the value it replaced
+type LabelChange struct {
+	Node     string `json:"node"`
+	Label    string `json:"label"`
+	Value    string `json:"value"`
+	Had      bool   `json:"had"` // the node carried the label before, with Previous as value
+	Previous string `json:"previous,omitempty"`
+}
+
+// Struct NodeLabeler sets node labels for a run, recording each change in the run state so it can be restored
+type NodeLabeler struct {
+	Cluster Cluster
+	State   *RunState
+}
+
+// Func label_arg returns the key=value argument of a label
+func label_arg(label string, value string) string {
+	return label + "=" + value
+}
+
+// Func Add sets a label on a node, leaving nodes that already carry the value untouched and unrecorded
+func (l *NodeLabeler) Add(ctx context.Context, node Node, label string, value string) error {
+	previous, had := node.Labels[label]
+	if had && previous == value {
+		return nil
+	}
+	log_fields("labeling node", "node", node.Name, "label", label_arg(label, value))
+	err := l.Cluster.LabelNode(ctx, node.Name, label_arg(label, value))
+	if err != nil {
+		return err
+	}
+	// Record the label so cleanup only restores labels this run changed
+	l.State.Labels = append(l.State.Labels, LabelChange{Node: node.Name, Label: label, Value: value, Had: had, Previous: previous})
+	return l.State.save()
+}
+
+// Func Restore puts back the labels the run changed, newest first, and returns the nodes restored
+func (l *NodeLabeler) Restore(ctx context.Context) ([]string, error) {
+	var restored []string
+	for len(l.State.Labels) > 0 {
+		c := l.State.Labels[len(l.State.Labels)-1]
+		arg := c.Label + "-"
+		if c.Had {
+			arg = label_arg(c.Label, c.Previous)
+		}
+		log_fields("restoring label", "node", c.Node, "label", arg)
+		err := l.Cluster.LabelNode(ctx, c.Node, arg)
+		if err != nil {
+			return restored, err
+		}
+		if !exists(restored, c.Node) {
+			restored = append(restored, c.Node)
+		}
+		l.State.Labels = l.State.Labels[:len(l.State.Labels)-1]
+		err = l.State.save()
+		if err != nil {
+			return restored, err
+		}
+	}
+	return restored, nil
+}
+
+// Struct NodeSelection is the policy picking the workers labeled worker-spk
+type NodeSelection struct {
+	Policy    string   // first, least-loaded, rack or list
+	Rack      string   // value of RackLabel for the rack policy
+	RackLabel string   // node label holding the rack
+	Nodes     []string // node names for the list policy, in order
+}
+
+// Node selection policies
+var selection_policies = []string{"first", "least-loaded", "rack", "list"}
+
+// Default label holding the rack of a node
+const default_rack_label = "topology.kubernetes.io/zone"
+
+// Func node_selection_flags registers the node selection flags on a flag set
+func node_selection_flags(fs *flag.FlagSet, s *NodeSelection) {
+	*s = NodeSelection{Policy: "first", RackLabel: default_rack_label}
+	fs.StringVar(&s.Policy, "select", s.Policy, "policy picking workers to label worker-spk: "+strings.Join(selection_policies, ", "))
+	fs.StringVar(&s.Rack, "rack", "", "rack of the workers labeled with -select rack")
+	fs.StringVar(&s.RackLabel, "rack-label", s.RackLabel, "node label holding the rack")
+	fs.Func("select-nodes", "comma separated workers labeled with -select list, in order", func(v string) error {
+		s.Nodes = strings.Split(v, ",")
+		return nil
+	})
+}
+
+// Func validate checks the policy is known and has what it needs
+func (s NodeSelection) validate() error {
+	switch s.Policy {
+	case "first", "least-loaded":
+		return nil
+	case "rack":
+		if s.Rack == "" {
+			return fmt.Errorf("node selection rack needs flag 'rack'")
+		}
+		return nil
+	case "list":
+		if len(s.Nodes) == 0 {
+			return fmt.Errorf("node selection list needs flag 'select-nodes'")
+		}
+		var seen []string
+		for _, n := range s.Nodes {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				return fmt.Errorf("flag 'select-nodes' has an empty node name")
+			}
+			if exists(seen, n) {
+				return fmt.Errorf("node %s is listed twice in flag 'select-nodes'", n)
+			}
+			seen = append(seen, n)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown node selection %q, use %s", s.Policy, strings.Join(selection_policies, ", "))
+}
+
+// Func order filters and orders the candidate workers by the policy, load is the pod count per node for least-loaded
+func (s NodeSelection) order(candidates []Node, load map[string]int) ([]Node, error) {
+	var ordered []Node
+	switch s.Policy {
+	case "first":
+		ordered = append(ordered, candidates...)
+	case "least-loaded":
+		ordered = append(ordered, candidates...)
+		sort.SliceStable(ordered, func(i, j int) bool { return load[ordered[i].Name] < load[ordered[j].Name] })
+	case "rack":
+		for _, n := range candidates {
+			if n.Labels[s.RackLabel] == s.Rack {
+				ordered = append(ordered, n)
+			}
+		}
+	case "list":
+		// Duplicates would label a node twice and leave the plan a node short
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		for _, name := range s.Nodes {
+			found := false
+			for _, n := range candidates {
+				if n.Name == strings.TrimSpace(name) {
+					ordered = append(ordered, n)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("node %s is not a ready worker outside worker-lb", name)
+			}
+		}
+	default:
+		return nil, s.validate()
+	}
+	return ordered, nil
+}
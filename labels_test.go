@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Func node_names returns the names of nodes in order
+func node_names(nodes []Node) string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestNodeSelectionOrder(t *testing.T) {
+	candidates := spk_nodes(5, 0)
+	for i := range candidates {
+		candidates[i].Labels[default_rack_label] = []string{"r1", "r2"}[i%2]
+	}
+	load := map[string]int{"worker-a": 30, "worker-b": 10, "worker-c": 30, "worker-d": 0}
+
+	tests := []struct {
+		name string
+		sel  NodeSelection
+		want string
+		err  string
+	}{
+		{name: "first", sel: NodeSelection{Policy: "first"}, want: "worker-a,worker-b,worker-c,worker-d,worker-e"},
+		// worker-e has no pods, ties keep node order
+		{name: "least loaded", sel: NodeSelection{Policy: "least-loaded"}, want: "worker-d,worker-e,worker-b,worker-a,worker-c"},
+		{name: "rack", sel: NodeSelection{Policy: "rack", Rack: "r2", RackLabel: default_rack_label}, want: "worker-b,worker-d"},
+		{name: "rack without nodes", sel: NodeSelection{Policy: "rack", Rack: "r3", RackLabel: default_rack_label}, want: ""},
+		{name: "list keeps its order", sel: NodeSelection{Policy: "list", Nodes: []string{"worker-c", " worker-a", "worker-e"}}, want: "worker-c,worker-a,worker-e"},
+		{name: "list with an unknown node", sel: NodeSelection{Policy: "list", Nodes: []string{"worker-a", "worker-z"}}, err: "node worker-z is not a ready worker"},
+		{name: "list with a duplicate", sel: NodeSelection{Policy: "list", Nodes: []string{"worker-a", "worker-b", "worker-a "}}, err: "node worker-a is listed twice"},
+		{name: "unknown policy", sel: NodeSelection{Policy: "random"}, err: "unknown node selection"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := tt.sel.order(candidates, load)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := node_names(ordered); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeSelectionValidate(t *testing.T) {
+	tests := []struct {
+		sel NodeSelection
+		err string
+	}{
+		{NodeSelection{Policy: "first"}, ""},
+		{NodeSelection{Policy: "rack"}, "needs flag 'rack'"},
+		{NodeSelection{Policy: "list"}, "needs flag 'select-nodes'"},
+		{NodeSelection{Policy: "list", Nodes: []string{"worker-a", ""}}, "empty node name"},
+		{NodeSelection{Policy: "list", Nodes: []string{"worker-a", "worker-a"}}, "listed twice"},
+	}
+	for _, tt := range tests {
+		err := tt.sel.validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%+v: %v", tt.sel, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: got error %v, want %q", tt.sel, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Struct LeftoverScan is a resource searched for objects of earlier runs
+type LeftoverScan struct {
+	Resource string
+	Prefixes []string // names of objects without a uuid label that still belong to a run, nil to only find labeled objects
+}
+
+// Names of sr-iov networks created by the serving init job
+var sriov_network_prefixes = []string{"sriov-net"}
+
+// Iteration suffix of namespaces like served-ns-3 given without namespacedIterations
+var iteration_suffix = regexp.MustCompile(`-\d+$`)
+
+// Name part following a prefix for an iteration
+var iteration_only = regexp.MustCompile(`^-\d+$`)
+
+// Struct LeftoverGroup is the objects of one earlier run, UUID is empty for objects matched by name only
+type LeftoverGroup struct {
+	UUID    string
+	Objects map[string][]string // objects per resource
+}
+
+// Func count returns the number of objects in the group
+func (g LeftoverGroup) count() int {
+	n := 0
+	for _, o := range g.Objects {
+		n += len(o)
+	}
+	return n
+}
+
+// Func workload_prefixes returns the namespace prefixes the jobs of workload configs create
+func workload_prefixes(opts RenderOptions, configs ...string) ([]string, error) {
+	var prefixes []string
+	for _, c := range configs {
+		opts.Workload = c
+		_, cfg, err := render_config(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range cfg.Jobs {
+			prefix := job.Namespace
+			if !job.NamespacedIterations {
+				// Fixed namespaces like openshift-sriov-network-operator are not the run's own
+				if !iteration_suffix.MatchString(prefix) {
+					continue
+				}
+				prefix = iteration_suffix.ReplaceAllString(prefix, "")
+			}
+			if prefix != "" && !exists(prefixes, prefix) {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+// Func has_prefix checks if a name is one of the prefixes or a prefix followed by an iteration
+func has_prefix(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if name == p || (strings.HasPrefix(name, p+"-") && iteration_only.MatchString(name[len(p):])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Func leftover_scans returns the resources searched for leftovers with the namespace prefixes of the workload
+func leftover_scans(namespace_prefixes []string) []LeftoverScan {
+	var scans []LeftoverScan
+	for _, o := range delete_objects {
+		scans = append(scans, LeftoverScan{Resource: o.Resource})
+	}
+	scans = append(scans, LeftoverScan{Resource: sriov_network_object.Resource, Prefixes: sriov_network_prefixes})
+	scans = append(scans, LeftoverScan{Resource: namespace_object.Resource, Prefixes: namespace_prefixes})
+	return scans
+}
+
+// Func find_leftovers groups objects labeled with a kube-burner uuid, or named like the run's objects, by uuid, ignoring the uuids in exclude
+func find_leftovers(ctx context.Context, cluster Cluster, scans []LeftoverScan, exclude []string) ([]LeftoverGroup, error) {
+	groups := make(map[string]*LeftoverGroup)
+	add := func(uuid string, resource string, object string) {
+		g, ok := groups[uuid]
+		if !ok {
+			g = &LeftoverGroup{UUID: uuid, Objects: make(map[string][]string)}
+			groups[uuid] = g
+		}
+		g.Objects[resource] = append(g.Objects[resource], object)
+	}
+
+	for _, s := range scans {
+		// Without name prefixes only labeled objects are listed, so pods of every namespace are not read
+		selector := "kube-burner-uuid"
+		if s.Prefixes != nil {
+			selector = ""
+		}
+		objects, err := cluster.LabeledObjects(ctx, s.Resource, selector, "kube-burner-uuid")
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objects {
+			if exists(exclude, o.Value) && o.Value != "" {
+				continue
+			}
+			name := o.Object[strings.LastIndex(o.Object, "/")+1:]
+			if o.Value == "" && !has_prefix(name, s.Prefixes) {
+				continue
+			}
+			add(o.Value, s.Resource, o.Object)
+		}
+	}
+
+	var found []LeftoverGroup
+	for _, g := range groups {
+		found = append(found, *g)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].UUID < found[j].UUID })
+	return found, nil
+}
+
+// Func print_leftovers writes a table of the leftovers per uuid and resource with an example object
+func print_leftovers(groups []LeftoverGroup) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UUID\tRESOURCE\tCOUNT\tEXAMPLE")
+	for _, g := range groups {
+		uuid := g.UUID
+		if uuid == "" {
+			uuid = "(unlabeled)"
+		}
+		var resources []string
+		for r := range g.Objects {
+			resources = append(resources, r)
+		}
+		sort.Strings(resources)
+		for _, r := range resources {
+			objects := g.Objects[r]
+			sort.Strings(objects)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", uuid, r, len(objects), objects[0])
+		}
+	}
+	return w.Flush()
+}
+
+// Func clean_leftovers runs cleanup for every leftover uuid and deletes unlabeled objects matched by name
+func clean_leftovers(ctx context.Context, cluster Cluster, burner KubeBurner, groups []LeftoverGroup, qps int, burst int) error {
+	for _, g := range groups {
+		if g.UUID == "" {
+			// Namespaces last, deleting them also removes what is left inside
+			var resources []string
+			for r := range g.Objects {
+				if r != namespace_object.Resource {
+					resources = append(resources, r)
+				}
+			}
+			sort.Strings(resources)
+			if _, ok := g.Objects[namespace_object.Resource]; ok {
+				resources = append(resources, namespace_object.Resource)
+			}
+			for _, r := range resources {
+				log_fields("deleting unlabeled leftovers", "resource", r, "count", len(g.Objects[r]))
+				err := cluster.DeleteObjects(ctx, r, g.Objects[r])
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		log_fields("cleaning up leftovers", "uuid", g.UUID, "objects", g.count())
+		c := &Cleanup{UUID: g.UUID, Namespaces: true, Sriov: true, Serving: true, Unlabel: true, QPS: qps, Burst: burst, Cluster: cluster, Burner: burner}
+		_, _, err := c.Run(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Func confirm asks a yes or no question on the terminal, false when stdin is not a terminal
+func confirm(question string) bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// Func leftovers checks the cluster for objects of earlier runs and cleans them up or aborts, depending on Config.Leftovers
+func (o *Orchestrator) leftovers(ctx context.Context) error {
+	plan, err := o.ip_plan()
+	if err != nil {
+		return err
+	}
+	opts := RenderOptions{Scale: o.Config.Scale, BFD: o.Config.BFD, QPS: o.Config.QPS, Burst: o.Config.Burst, IPPlan: plan}
+	prefixes, err := workload_prefixes(opts, o.Config.ServingInit, o.Config.Workload)
+	if err != nil {
+		return err
+	}
+	groups, err := find_leftovers(ctx, o.Cluster, leftover_scans(prefixes), []string{o.Config.UUID, o.Config.ServingUUID})
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		log_fields("no leftovers of earlier runs found", "namespace_prefixes", strings.Join(prefixes, ","))
+		return nil
+	}
+	err = print_leftovers(groups)
+	if err != nil {
+		return err
+	}
+
+	action := o.Config.Leftovers
+	if action == "ask" {
+		action = "abort"
+		if confirm(fmt.Sprintf("Clean up the objects of %d earlier runs before starting?", len(groups))) {
+			action = "cleanup"
+		}
+	}
+	switch action {
+	case "ignore":
+		log_fields("ignoring leftovers of earlier runs", "groups", len(groups))
+		return nil
+	case "cleanup":
+		return clean_leftovers(ctx, o.Cluster, o.Burner, groups, o.Config.QPS, o.Config.Burst)
+	}
+	return fmt.Errorf("objects of %d earlier runs found, remove them with cleanup or rerun with '-leftovers cleanup'", len(groups))
+}
+
+// Func leftovers_cmd lists objects of earlier runs grouped by uuid and optionally removes them
+func leftovers_cmd(args []string) error {
+	fs := flag.NewFlagSet("leftovers", flag.ExitOnError)
+	var opts RenderOptions
+	workload := fs.String("workload", "workload/cfg_icni2_cluster_density2.yml", "workload config whose namespace prefixes are matched")
+	serving_init := fs.String("serving-init", "workload/cfg_icni2_serving_resource_init.yml", "kube-burner config creating the serving resources")
+	fs.IntVar(&opts.Scale, "scale", env_int("SCALE", 1), "scale factor the workload configs are rendered with")
+	fs.IntVar(&opts.QPS, "qps", env_int("QPS", 20), "kube-burner qps of the delete jobs")
+	fs.IntVar(&opts.Burst, "burst", env_int("BURST", 20), "kube-burner burst of the delete jobs")
+	exclude := fs.String("exclude", "", "comma separated uuids whose objects are not leftovers")
+	clean := fs.Bool("cleanup", false, "remove the leftovers")
+	kubeconfig := fs.String("kubeconfig", env_default("KUBECONFIG", "/home/kni/clusterconfigs/auth/kubeconfig"), "kubeconfig of the cluster")
+	var burner ExecKubeBurner
+	kube_burner_flags(fs, &burner)
+	fs.Parse(args)
+
+	prefixes, err := workload_prefixes(opts, *serving_init, *workload)
+	if err != nil {
+		return err
+	}
+	cluster := &OcCluster{Kubeconfig: *kubeconfig}
+	ctx := context.Background()
+	groups, err := find_leftovers(ctx, cluster, leftover_scans(prefixes), strings.Split(*exclude, ","))
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		log_fields("no leftovers of earlier runs found", "namespace_prefixes", strings.Join(prefixes, ","))
+		return nil
+	}
+	err = print_leftovers(groups)
+	if err != nil {
+		return err
+	}
+	if *clean {
+		return clean_leftovers(ctx, cluster, burner, groups, opts.QPS, opts.Burst)
+	}
+	return fmt.Errorf("objects of %d earlier runs found, remove them with flag '-cleanup'", len(groups))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWorkloadPrefixes(t *testing.T) {
+	opts := RenderOptions{Scale: 1, QPS: 20, Burst: 20}
+	tests := []struct {
+		configs []string
+		want    []string
+	}{
+		// The networks job creates its objects in openshift-sriov-network-operator, which is never a prefix
+		{[]string{"workload/cfg_icni2_serving_resource_init.yml"}, []string{"served-ns", "serving-ns"}},
+		// Jobs of served-ns-1, served-ns-2, ... without namespacedIterations give their common prefix
+		{[]string{"workload/cfg_icni2_cluster_density2.yml"}, []string{"served-ns"}},
+		{[]string{"workload/cfg_icni2_serving_resource_init.yml", "workload/cfg_icni2_node_density2.yml"}, []string{"served-ns", "serving-ns"}},
+	}
+	for _, tt := range tests {
+		got, err := workload_prefixes(opts, tt.configs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("workload_prefixes(%v) = %v, want %v", tt.configs, got, tt.want)
+		}
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	prefixes := []string{"served-ns", "serving-ns"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"served-ns-1", true},
+		{"served-ns-175", true},
+		{"serving-ns-35", true},
+		{"served-ns", true},
+		{"served-ns-foo", false},
+		{"served-ns-1-debug", false},
+		{"served-ns-", false},
+		{"served-nsx-1", false},
+		{"my-served-ns-1", false},
+		{"openshift-sriov-network-operator", false},
+	}
+	for _, tt := range tests {
+		if got := has_prefix(tt.name, prefixes); got != tt.want {
+			t.Errorf("has_prefix(%q) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+	if has_prefix("served-ns-1", nil) {
+		t.Error("has_prefix matched without prefixes")
+	}
+}
+
+// Func leftover_cluster returns a cluster with objects of runs u0 and u1, unlabeled run objects and unrelated objects
+func leftover_cluster() *FakeCluster {
+	return &FakeCluster{Leftovers: []LabeledObject{
+		{Object: "pods/served-ns-1/pod-1", Value: "u0"},
+		{Object: "pods/served-ns-1/pod-2", Value: "u1"},
+		{Object: "pods/default/unrelated"},
+		{Object: "services/served-ns-1/svc-1", Value: "u0"},
+		{Object: "namespaces/served-ns-1", Value: "u0"},
+		{Object: "namespaces/served-ns-2"},
+		{Object: "namespaces/served-ns-foo"},
+		{Object: "namespaces/serving-ns-3"},
+		{Object: "namespaces/openshift-sriov-network-operator"},
+		{Object: "namespaces/default"},
+		{Object: "sriovnetworks/openshift-sriov-network-operator/sriov-net-1"},
+		{Object: "sriovnetworks/openshift-sriov-network-operator/sriov-net-2", Value: "s0"},
+		{Object: "sriovnetworks/openshift-sriov-network-operator/mgmt-net"},
+	}}
+}
+
+// Func group_objects returns the sorted objects per uuid and resource of leftover groups
+func group_objects(groups []LeftoverGroup) map[string]map[string][]string {
+	found := make(map[string]map[string][]string)
+	for _, g := range groups {
+		found[g.UUID] = make(map[string][]string)
+		for r, objects := range g.Objects {
+			sort.Strings(objects)
+			found[g.UUID][r] = objects
+		}
+	}
+	return found
+}
+
+func TestFindLeftovers(t *testing.T) {
+	unlabeled := map[string][]string{
+		"namespaces":    {"namespaces/served-ns-2", "namespaces/serving-ns-3"},
+		"sriovnetworks": {"sriovnetworks/openshift-sriov-network-operator/sriov-net-1"},
+	}
+	tests := []struct {
+		name    string
+		exclude []string
+		want    map[string]map[string][]string
+	}{
+		{"every run", nil, map[string]map[string][]string{
+			"": unlabeled,
+			"u0": {
+				"pods":       {"pods/served-ns-1/pod-1"},
+				"services":   {"services/served-ns-1/svc-1"},
+				"namespaces": {"namespaces/served-ns-1"},
+			},
+			"u1": {"pods": {"pods/served-ns-1/pod-2"}},
+			"s0": {"sriovnetworks": {"sriovnetworks/openshift-sriov-network-operator/sriov-net-2"}},
+		}},
+		// The current run and its serving init job are excluded, an empty uuid in the list keeps unlabeled objects
+		{"excluded uuids", []string{"u1", "s0", ""}, map[string]map[string][]string{
+			"": unlabeled,
+			"u0": {
+				"pods":       {"pods/served-ns-1/pod-1"},
+				"services":   {"services/served-ns-1/svc-1"},
+				"namespaces": {"namespaces/served-ns-1"},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := find_leftovers(context.Background(), leftover_cluster(), leftover_scans([]string{"served-ns", "serving-ns"}), tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := group_objects(groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindLeftoversNone(t *testing.T) {
+	cluster := &FakeCluster{Leftovers: []LabeledObject{
+		{Object: "pods/served-ns-1/pod-1", Value: "u1"},
+		{Object: "namespaces/served-ns-foo"},
+		{Object: "namespaces/openshift-sriov-network-operator"},
+	}}
+	groups, err := find_leftovers(context.Background(), cluster, leftover_scans([]string{"served-ns"}), []string{"u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("got leftovers %v", group_objects(groups))
+	}
+}
+
+func TestCleanLeftovers(t *testing.T) {
+	in_temp_dir(t)
+	cluster := leftover_cluster()
+	groups, err := find_leftovers(context.Background(), cluster, leftover_scans([]string{"served-ns", "serving-ns"}), []string{"u1", "s0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	burner := &FakeKubeBurner{}
+	err = clean_leftovers(context.Background(), cluster, burner, groups, 20, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unlabeled namespaces go last so the objects in them are deleted first
+	if !reflect.DeepEqual(cluster.Deleted, []string{"sriovnetworks", "namespaces"}) {
+		t.Errorf("deleted unlabeled %v, want sriovnetworks then namespaces", cluster.Deleted)
+	}
+	if len(burner.Configs) != 1 || burner.Configs[0] != "runs/u0/cfg_del.yml" {
+		t.Errorf("ran kube-burner with %v, want the delete job of u0", burner.Configs)
+	}
+}
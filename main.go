@@ -9,12 +9,14 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cristoper/gsheet/gdrive"
 	"github.com/cristoper/gsheet/gsheets"
+	"google.golang.org/api/sheets/v4"
 )
 
 var uuid string
@@ -22,39 +24,161 @@ var google_parent_id string
 var push_google bool
 var google_sheet_file_name string
 var google_sheet_id string
+var regression_pct float64
+var append_google bool
+var sheet_id_flag string
+var sheet_name_template string
+var rollover string
+var workload_file string
+var cluster_name string
+var time_zone string
+var credentials_file string
+var run_manifest string
+var summary_window string
+var run_meta RunMetadata
+var token_file string
 var gdrive_svc *gdrive.Service
 var gsheet_svc *gsheets.Service
+var values_svc *sheets.SpreadsheetsValuesService
+
+// Header of the summary csv file and google sheet
+var summary_header = append([]string{"Iteration", "StartTime", "EndTime", "UUID", "MasterCPU", "WorkerCPU", "MasterMemoryActive", "WorkerMemoryActive", "MasterMemoryAvailable", "WorkerMemoryAvailable", "MasterMemoryCached", "WorkerMemoryCached", "KubeletCPU", "KubeletMemory", "CrioCPU", "CrioMemory", "API99thLatency", "PodCount", "ServiceCount", "NamespaceCount", "DeploymentCount", "99thEtcdDiskWalFsyncDurationSeconds", "EtcdLeaderChangeRate", "PodReadyLatencyP99"}, metadata_header...)
+
+// Subcommands run instead of the default summary, keyed by the first argument
+var subcommands map[string]func(args []string) error
 
 func init() {
 
+	// Use the bundle directory as the working directory before anything reads relative paths
+	if err := use_bundle_dir(); err != nil {
+		log.Fatal("Unable to use " + bundle_env + " directory: " + err.Error())
+	}
+
+	subcommands = map[string]func(args []string) error{
+		"run":       run_cmd,
+		"list-runs": list_runs_cmd,
+		"cleanup":   cleanup_cmd,
+		"render":    render_cmd,
+		"counts":    counts_cmd,
+		"generate":  generate_cmd,
+		"validate":  validate_cmd,
+		"workloads": workloads_cmd,
+		"ipplan":    ipplan_cmd,
+		"frr":       frr_cmd,
+		"plan":      plan_cmd,
+		"preflight": preflight_cmd,
+		"leftovers": leftovers_cmd,
+		"jobs":      jobs_cmd,
+	}
+}
+
+// Func summary_flags parses and checks the flags of the default summary
+func summary_flags() {
 	u := flag.String("uuid", "", "uuid being used for workload")
 	p := flag.String("parent", "", "google sheet parent id")
 	g := flag.Bool("gdocs", false, "bool to push csv file to google docs, default is false")
+	t := flag.String("thresholds", "", "comma separated column thresholds to highlight in google sheet, e.g. MasterCPU=80,API99thLatency=1")
+	r := flag.Float64("regress-pct", 10, "percent change versus the previous iteration highlighted as a regression in google sheet")
+	a := flag.Bool("append", false, "bool to append only the new summary row to the google sheet instead of overwriting it, default is false")
+	sid := flag.String("sheet-id", "", "existing google sheet id to write to instead of the one found or created for the sheet name")
+	sn := flag.String("sheet-name", "", "template for the sheet name, e.g. {{.Workload}}-{{.Week}}; overrides the rollover policy")
+	ro := flag.String("rollover", "daily", "when to start a new sheet: daily, weekly, per-workload, per-cluster or never")
+	wl := flag.String("workload", "", "kube-burner workload config used for the run")
+	cl := flag.String("cluster", "", "cluster name used in sheet names, default is the api server host of the logged in cluster")
+	tz := flag.String("tz", "Local", "time zone used for dates in sheet names, e.g. UTC")
+	cr := flag.String("credentials", "", "google service account, external account or oauth client secrets json file, default is GOOGLE_APPLICATION_CREDENTIALS or application default credentials")
+	mf := flag.String("manifest", "", "run manifest json with workload, scale, bfd, qps, burst and kubeBurnerRelease, default is the run registry entry for the uuid")
+	sc := flag.Int("scale", 1, "scale factor of the run")
+	bfd := flag.Bool("bfd", false, "bfd was enabled for the run")
+	qps := flag.Int("qps", 20, "kube-burner qps of the run")
+	burst := flag.Int("burst", 20, "kube-burner burst of the run")
+	kbr := flag.String("kube-burner-release", "", "kube-burner release used for the run")
+	win := flag.String("window", "all", "part of the run the summary covers: all metric samples, or only the samples of the workload phase")
+	tf := flag.String("token-file", default_token_file(), "file caching the oauth user token when using oauth client secrets with flag 'credentials'")
 	flag.Parse()
 
 	uuid = derefString(u)
 	google_parent_id = derefString(p)
 	push_google = *g
+	regression_pct = *r
+	append_google = *a
+	sheet_id_flag = *sid
+	sheet_name_template = *sn
+	rollover = *ro
+	workload_file = *wl
+	cluster_name = *cl
+	time_zone = *tz
+	credentials_file = *cr
+	token_file = *tf
+	run_manifest = *mf
+	summary_window = *win
+
+	// Run parameters come from the manifest or registry, with flags given on the command line taking precedence
+	meta, err := run_metadata(uuid, run_manifest)
+	if err != nil {
+		log.Fatal(err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "workload":
+			meta.Workload = workload_file
+		case "scale":
+			meta.Scale = strconv.Itoa(*sc)
+		case "bfd":
+			meta.BFD = strconv.FormatBool(*bfd)
+		case "qps":
+			meta.QPS = strconv.Itoa(*qps)
+		case "burst":
+			meta.Burst = strconv.Itoa(*burst)
+		case "kube-burner-release":
+			meta.KubeBurnerRelease = *kbr
+		}
+	})
+	if workload_file == "" {
+		workload_file = meta.Workload
+	}
+	run_meta = meta
 
 	if uuid == "" {
 		log.Fatal("Please provide uuid using flag '-uuid'")
 	}
+	if !exists(summary_windows, summary_window) {
+		log.Fatal("Unknown window " + summary_window + " given with flag 'window', use " + strings.Join(summary_windows, " or "))
+	}
+	if push_google == false && append_google == true {
+		log.Fatal("Flag 'append' was set, but flag 'gdocs' was set to false or unset and left to default.")
+	}
+	if push_google == false && sheet_id_flag != "" {
+		log.Fatal("Sheet ID given with flag 'sheet-id', but flag 'gdocs' was set to false or unset and left to default.")
+	}
 	if push_google == false && google_parent_id != "" {
 		log.Fatal("Parent ID given with flag 'parent', but flag 'gdocs' was set to false or unset and left to default.")
 	}
-	if push_google == true {
-		check_env := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-		if check_env == "" {
-			log.Fatal("Env var GOOGLE_APPLICATION_CREDENTIALS has not been set with location of service account yaml file. See https://github.com/cristoper/gsheet#authentication-and-authorization")
-		}
+	if _, err := os.Stat(credentials_file); push_google == true && credentials_file != "" && err != nil {
+		log.Fatal("Credentials file " + credentials_file + " given with flag 'credentials' does not exist")
 	}
-	if push_google == true && google_parent_id == "" {
+	if push_google == false && credentials_file != "" {
+		log.Fatal("Credentials file given with flag 'credentials', but flag 'gdocs' was set to false or unset and left to default.")
+	}
+	if push_google == true && google_parent_id == "" && sheet_id_flag == "" {
 		log.Fatal("Google Docs set to true with flag 'gdoc', but no parent id given with flag 'parent'!")
 	}
+	if err := parse_thresholds(*t); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func main() {
 
+	// Run subcommand if one was given
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			error_check(cmd(os.Args[2:]))
+			return
+		}
+	}
+	summary_flags()
+
 	// Determine present working directory
 	wd, err := os.Getwd()
 	error_check(err)
@@ -73,14 +197,23 @@ func main() {
 		error_check(err)
 	}
 
-	// Determine Date and set to var for file names
-	year, month, day := time.Now().Date()
-	store_sheetid := "Sheetid-" + strconv.Itoa(year) + "-" + month.String() + "-" + strconv.Itoa(day) + ".txt"
-	iteration_count := "iteration_count-" + strconv.Itoa(year) + "-" + month.String() + "-" + strconv.Itoa(day) + ".txt"
-	google_sheet_file_name = strconv.Itoa(year) + "-" + month.String() + "-" + strconv.Itoa(day) + ".csv"
+	// Determine sheet name from rollover policy and set to var for file names
+	loc, err := time.LoadLocation(time_zone)
+	error_check(err)
+	if cluster_name == "" && (rollover == "per-cluster" || strings.Contains(sheet_name_template, ".Cluster")) {
+		cluster_name = current_cluster()
+	}
+	key, err := sheet_key(rollover, sheet_name_template, sheet_name_data(time.Now().In(loc), workload_file, cluster_name))
+	error_check(err)
+	log.Println("Using sheet name", key)
+	store_sheetid := "Sheetid-" + key + ".txt"
+	iteration_count := "iteration_count-" + key + ".txt"
+	google_sheet_file_name = key + ".csv"
 
 	// Create gdrive and gsheet svc and create/update csv file locally
 	if push_google == true {
+		err = setup_credentials(credentials_file, token_file)
+		error_check(err)
 		gd, gs, err := google_docs(wd, store_sheetid, google_sheet_file_name)
 		error_check(err)
 		gdrive_svc = gd
@@ -101,17 +234,29 @@ func main() {
 	log.Println("Attempting to retrieve json files with uuid", uuid)
 	files_req := []string{"nodeCPU", "nodeMemoryActive", "nodeMemoryAvailable", "nodeMemoryCached", "kubeletMemory", "kubeletCPU", "crioCPU", "crioMemory", "API99thLatency", "podStatusCount", "serviceCount", "namespaceCount", "deploymentCount", "99thEtcdDiskWalFsyncDurationSeconds", "etcdLeaderChangesRate"}
 	json_files := retrieve_json_files(files_req, uuid)
+	json_files = append(json_files, retrieve_pod_latency_files(uuid)...)
 	log.Println("Found", len(json_files), "files with uuid", uuid)
 	log.Println(json_files)
 
 	// Unmarshall json data and write to csv file
 	log.Println("Attempting to unmarshal json data and calculate summary information to write to csv file", google_sheet_file_name)
-	err = csv_file(wd, json_files, uuid, google_sheet_file_name, iteration)
+	cluster_facts(wd, uuid, &run_meta)
+	var window *RunWindow
+	if summary_window == "workload" {
+		window, err = workload_window(wd, uuid, run_meta)
+		error_check(err)
+	}
+	err = csv_file(wd, json_files, uuid, google_sheet_file_name, iteration, run_meta, window)
 	error_check(err)
 	log.Println("Succesfully wrote summary data to csv file", google_sheet_file_name)
 
 	// Upload csv file to Google Docs
-	if push_google == true {
+	if push_google == true && append_google == true {
+		log.Println("Attempting to append summary row to google sheet")
+		gs_id, err := append_to_google_sheet(wd, google_sheet_file_name, google_parent_id, google_sheet_id, store_sheetid, gdrive_svc, gsheet_svc, values_svc)
+		error_check(err)
+		google_sheet_id = gs_id
+	} else if push_google == true {
 		log.Println("Attempting to write csv file to google sheet")
 		gs_id, err := write_to_google_sheet(wd, google_sheet_file_name, google_parent_id, google_sheet_id, store_sheetid, gdrive_svc, gsheet_svc)
 		error_check(err)
@@ -124,6 +269,15 @@ func main() {
 	json_files = retrieve_json_files(files_req, uuid)
 	err = max_node_job_vals(wd, json_files, uuid)
 	error_check(err)
+
+	// Write start, end and duration of each kube-burner job
+	log.Println("Attempting to write job times to /gsheet/jobs-" + uuid + ".csv")
+	err = job_timing_csv(wd, uuid)
+	error_check(err)
+	if push_google == true {
+		err = upload_job_timings(wd, uuid, google_sheet_id, gsheet_svc)
+		error_check(err)
+	}
 	log.Println("Completed Successfully!")
 }
 
@@ -134,13 +288,11 @@ func retrieve_sheetid(wd string, store_sheetid string, file_name string, gsheet_
 	if check_file_exists(wd, "/gsheet/"+store_sheetid) {
 		// Return google sheet id
 		log.Println("Google Sheet file already exists from previous iteration, retrieving sheet id!")
-		cmd := "cat gsheet/" + store_sheetid
-		out, err := exec.Command("bash", "-c", cmd).Output()
+		out, err := os.ReadFile(filepath.Join(wd, "gsheet", store_sheetid))
 		if err != nil {
 			return "", err
 		}
-		s := string(out)
-		sheetid := strings.TrimSpace(s)
+		sheetid := strings.TrimSpace(string(out))
 		return sheetid, nil
 	}
 	// Create google sheet and return sheet id
@@ -177,17 +329,33 @@ func google_docs(wd string, store_sheetid string, file_name string) (*gdrive.Ser
 
 	// Check for existing google sheet and write to local csv if it exists
 	log.Println("Determining if google sheet id exists already from previous runs today to append to")
-	google_sheet_id, err = retrieve_sheetid(wd, store_sheetid, google_sheet_file_name, gsheet_svc)
-	error_check(err)
+	if sheet_id_flag != "" {
+		log.Println("Using google sheet id", sheet_id_flag, "given with flag 'sheet-id'")
+		google_sheet_id = sheet_id_flag
+	} else {
+		google_sheet_id, err = retrieve_sheetid(wd, store_sheetid, google_sheet_file_name, gsheet_svc)
+		error_check(err)
+	}
+
+	// Appending only sends the new row, so the sheet is not downloaded
+	if append_google {
+		values_svc, err = values_svc_create()
+		if err != nil {
+			return gdrive_svc, gsheet_svc, err
+		}
+		return gdrive_svc, gsheet_svc, nil
+	}
 
 	// Delete csv file if it exists with old data
 	log.Println("Checking for exisitng csv file with the same name and removing to create a new one with up to date information")
 	_, err = os.Stat(f)
-	if err == nil {
-		log.Println("CSV filename " + file_name + " already exists: Removing existing file before proceeding!")
-		err = os.Remove(f)
-		if err != nil {
-			return gdrive_svc, gsheet_svc, err
+	if err == nil || sheet_id_flag != "" {
+		if err == nil {
+			log.Println("CSV filename " + file_name + " already exists: Removing existing file before proceeding!")
+			err = os.Remove(f)
+			if err != nil {
+				return gdrive_svc, gsheet_svc, err
+			}
 		}
 		// Create csv file
 		file, err := os.OpenFile(f, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
@@ -200,6 +368,11 @@ func google_docs(wd string, store_sheetid string, file_name string) (*gdrive.Ser
 		if err != nil {
 			return gdrive_svc, gsheet_svc, err
 		}
+		// An empty sheet leaves an empty file, remove it so the header is written
+		info, err := file.Stat()
+		if err == nil && info.Size() == 0 {
+			os.Remove(f)
+		}
 	}
 	return gdrive_svc, gsheet_svc, nil
 }
@@ -215,7 +388,7 @@ func local_csv(wd string, file_name string) error {
 			return err
 		}
 		w := csv.NewWriter(file)
-		sum_table := [][]string{{"Iteration", "StartTime", "EndTime", "UUID", "MasterCPU", "WorkerCPU", "MasterMemoryActive", "WorkerMemoryActive", "MasterMemoryAvailable", "WorkerMemoryAvailable", "MasterMemoryCached", "WorkerMemoryCached", "KubeletCPU", "KubeletMemory", "CrioCPU", "CrioMemory", "API99thLatency", "PodCount", "ServiceCount", "NamespaceCount", "DeploymentCount", "99thEtcdDiskWalFsyncDurationSeconds", "EtcdLeaderChangeRate"}}
+		sum_table := [][]string{summary_header}
 		for _, record := range sum_table {
 			if err := w.Write(record); err != nil {
 				if err != nil {
@@ -224,25 +397,81 @@ func local_csv(wd string, file_name string) error {
 			}
 			w.Flush()
 		}
+		return nil
 	}
-	return nil
+	return migrate_csv_header(f, summary_header)
+}
+
+// Func migrate_csv_header rewrites a csv file written with an older header so its rows line up with header, new columns are left empty
+func migrate_csv_header(f string, header []string) error {
+	r, err := os.Open(f)
+	if err != nil {
+		return err
+	}
+	csv_r := csv.NewReader(r)
+	csv_r.FieldsPerRecord = -1
+	rows, err := csv_r.ReadAll()
+	r.Close()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") == strings.Join(header, ",") {
+		return nil
+	}
+
+	log.Println("Header of csv file", f, "differs from the summary header, migrating its rows")
+	index := make(map[string]int)
+	for i, h := range header {
+		index[h] = i
+	}
+	for _, h := range rows[0] {
+		if _, ok := index[h]; !ok {
+			log.Println("Column", h, "of csv file", f, "is no longer in the summary, dropping it")
+		}
+	}
+	migrated := [][]string{header}
+	for _, row := range rows[1:] {
+		aligned := make([]string, len(header))
+		for i, h := range rows[0] {
+			if c, ok := index[h]; ok && i < len(row) {
+				aligned[c] = row[i]
+			}
+		}
+		migrated = append(migrated, aligned)
+	}
+
+	// Write next to the file and rename so an interrupted migration keeps the old rows
+	tmp := f + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	err = w.WriteAll(migrated)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, f)
 }
 
 // Func iteration finds or creates the iteration count
 func iteration(wd, iteration_file string) (string, error) {
 	f := "/gsheet/" + iteration_file
+	p := filepath.Join(wd, "gsheet", iteration_file)
 	if !(check_file_exists(wd, f)) {
 		log.Println("No " + iteration_file + " file found. Setting iteration to iteration_1 and creating file " + iteration_file)
-		cmd := "echo iteration_1 > " + "gsheet/" + iteration_file
-		_, err := exec.Command("bash", "-c", cmd).Output()
+		err := os.WriteFile(p, []byte("iteration_1\n"), 0644)
 		if err != nil {
 			return "", err
 		}
 		return "iteration_1", nil
 	}
 	log.Println(iteration_file + " found, retrieving iteration number to incrememnt!")
-	cmd := "cat " + "gsheet/" + iteration_file
-	out, err := exec.Command("bash", "-c", cmd).Output()
+	out, err := os.ReadFile(p)
 	if err != nil {
 		return "", err
 	}
@@ -252,8 +481,7 @@ func iteration(wd, iteration_file string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	cmd = "echo " + new_incrememnt + " > " + "gsheet/" + iteration_file
-	_, err = exec.Command("bash", "-c", cmd).Output()
+	err = os.WriteFile(p, []byte(new_incrememnt+"\n"), 0644)
 	if err != nil {
 		return "", err
 	}
@@ -280,6 +508,28 @@ func retrieve_json_files(files_req []string, uuid string) []string {
 	return json_fies
 }
 
+// Func retrieve_pod_latency_files finds pod latency summary files in collected-metrics that belong to uuid
+func retrieve_pod_latency_files(uuid string) []string {
+	var json_files []string
+	entries, err := os.ReadDir("collected-metrics")
+	if err != nil {
+		log.Println("Unable to read collected-metrics for pod latency files with error:", err)
+		return json_files
+	}
+	for _, e := range entries {
+		if !strings.Contains(e.Name(), "podLatency-summary") {
+			continue
+		}
+		// Pod latency files are named by job, so match the uuid on their contents
+		data, err := os.ReadFile("collected-metrics/" + e.Name())
+		if err != nil || !bytes.Contains(data, []byte(uuid)) {
+			continue
+		}
+		json_files = append(json_files, e.Name())
+	}
+	return json_files
+}
+
 // Func gdrive_svc creates a gsheets drive service
 func gdrive_svc_create() (*gdrive.Service, error) {
 	var empty *gdrive.Service
@@ -302,6 +552,16 @@ func gsheet_svc_create() (*gsheets.Service, error) {
 	return svc, nil
 }
 
+// Func values_svc_create creates a sheets values service for appending rows
+func values_svc_create() (*sheets.SpreadsheetsValuesService, error) {
+	var empty *sheets.SpreadsheetsValuesService
+	svc, err := sheets.NewService(context.TODO())
+	if err != nil {
+		return empty, err
+	}
+	return svc.Spreadsheets.Values, nil
+}
+
 // Func create_gs creates a new google spreadsheet
 func create_gs(file_name string, parent string, gdrive_svc *gdrive.Service) (string, error) {
 
@@ -314,35 +574,58 @@ func create_gs(file_name string, parent string, gdrive_svc *gdrive.Service) (str
 	return new_sheet.Id, nil
 }
 
+// Func new_sheet_id creates a google spreadsheet and stores its id in the sheetid txt file
+func new_sheet_id(file_name string, parent string, txt_path string, gdrive_svc *gdrive.Service) (string, error) {
+	sheet_id, err := create_gs(file_name, parent, gdrive_svc)
+	if err != nil {
+		return "", err
+	}
+	// Wrtie data from sheet id csv file to local new csv file
+	err = os.WriteFile(txt_path, []byte(sheet_id+"\n"), 0644)
+	if err != nil {
+		return "", err
+	}
+	return sheet_id, nil
+}
+
 // Func write_to_google_sheets creates a specified google sheet utilizing an existing csv file
 func write_to_google_sheet(wd string, file_name string, parent string, sheet_id string, txt_file string, gdrive_svc *gdrive.Service, gsheet_svc *gsheets.Service) (string, error) {
 	f := wd + "/gsheet/" + file_name
 	t := wd + "/gsheet/" + txt_file
 
 	if sheet_id == "" {
-		s, err := create_gs(file_name, parent, gdrive_svc)
+		s, err := new_sheet_id(file_name, parent, t, gdrive_svc)
 		if err != nil {
 			return "", err
 		}
 		sheet_id = s
-		// Wrtie data from sheet id csv file to local new csv file
-		cmd := "echo " + sheet_id + " > " + t
-		_, err = exec.Command("bash", "-c", cmd).Output()
-		if err != nil {
-			return "", err
-		}
 	}
 
 	r, err := os.Open(f)
 	if err != nil {
 		return "", err
 	}
+	defer r.Close()
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return "", err
+	}
 
 	log.Println("Writing csv data to google sheet id", sheet_id)
-	_, err = gsheet_svc.UpdateRangeCSV(sheet_id, "Sheet1", r)
+	_, err = gsheet_svc.UpdateRangeRaw(sheet_id, "Sheet1", sheet_values(rows))
 	if err != nil {
 		return "", err
 	}
+	if len(rows) > 0 {
+		err = format_sheet(sheet_id, "Sheet1", rows[0], gsheet_svc)
+		if err != nil {
+			return "", err
+		}
+		err = update_charts(sheet_id, "Sheet1", rows[0], gsheet_svc)
+		if err != nil {
+			return "", err
+		}
+	}
 	return sheet_id, nil
 }
 
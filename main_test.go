@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateCsvHeader(t *testing.T) {
+	// Headers written before PodReadyLatencyP99 and the run metadata columns were added
+	before_latency := summary_header[:23]
+	before_metadata := summary_header[:24]
+
+	tests := []struct {
+		name   string
+		header []string
+	}{
+		{"before pod latency", before_latency},
+		{"before run metadata", before_metadata},
+		{"current", summary_header},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := filepath.Join(t.TempDir(), "summary.csv")
+			row := make([]string, len(tt.header))
+			for i, h := range tt.header {
+				row[i] = "v-" + h
+			}
+			write_csv(t, f, [][]string{tt.header, row})
+
+			err := migrate_csv_header(f, summary_header)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A row of the current header appended after migration must read back with the others
+			file, err := os.OpenFile(f, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := csv.NewWriter(file)
+			w.Write(make([]string, len(summary_header)))
+			w.Flush()
+			file.Close()
+
+			rows := read_csv(t, f)
+			if len(rows) != 3 {
+				t.Fatalf("got %d rows, want 3", len(rows))
+			}
+			if strings.Join(rows[0], ",") != strings.Join(summary_header, ",") {
+				t.Errorf("header not migrated: %v", rows[0])
+			}
+			for i, h := range summary_header {
+				want := ""
+				if exists(tt.header, h) {
+					want = "v-" + h
+				}
+				if rows[1][i] != want {
+					t.Errorf("column %s = %q, want %q", h, rows[1][i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateCsvHeaderDropsUnknownColumns(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "summary.csv")
+	write_csv(t, f, [][]string{{"Iteration", "Removed", "UUID"}, {"iteration_1", "x", "u1"}})
+
+	err := migrate_csv_header(f, []string{"Iteration", "StartTime", "UUID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := read_csv(t, f)
+	if got := strings.Join(rows[1], ","); got != "iteration_1,,u1" {
+		t.Errorf("got row %q, want %q", got, "iteration_1,,u1")
+	}
+}
+
+// Func write_csv writes rows to a csv file
+func write_csv(t *testing.T, f string, rows [][]string) {
+	t.Helper()
+	file, err := os.Create(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	err = csv.NewWriter(file).WriteAll(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Func read_csv reads a csv file strictly, the way write_to_google_sheet does
+func read_csv(t *testing.T, f string) [][]string {
+	t.Helper()
+	file, err := os.Open(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Struct CountMetric maps an object kind to the collected metric counting it
+type CountMetric struct {
+	Kind   string
+	Metric string
+	Phase  string // label value summed for metrics split by phase, empty sums every series
+}
+
+// Collected count metrics compared with the rendered workload, see workload/metrics_full.yaml
+var count_metrics = []CountMetric{
+	{Kind: "Namespace", Metric: "namespaceCount", Phase: "Active"},
+	{Kind: "Pod", Metric: "podStatusCount", Phase: "Running"},
+	{Kind: "Service", Metric: "serviceCount"},
+	{Kind: "Deployment", Metric: "deploymentCount"},
+	{Kind: "Secret", Metric: "secretCount"},
+	{Kind: "ConfigMap", Metric: "configmapCount"},
+}
+
+// Struct CountCheck is the expected and observed count of one kind
+type CountCheck struct {
+	Kind     string
+	Expected int
+	Observed int
+	Found    bool // false when the metric was not collected for the run
+	Match    bool
+}
+
+// Func expected_counts computes the namespaces, pods, services, deployments, configmaps and secrets a rendered workload creates
+func expected_counts(r *Rendered, ns_secrets int, ns_configmaps int) map[string]int {
+	counts := make(map[string]int)
+	namespaces := 0
+	for ns := range r.Namespaces {
+		// Namespaces such as openshift-sriov-network-operator exist before the run
+		if strings.HasPrefix(ns, "openshift-") {
+			continue
+		}
+		namespaces++
+	}
+	counts["Namespace"] = namespaces
+	// Openshift creates the builder, default and deployer service accounts in every new namespace, each with a token
+	// and a dockercfg secret, and the kube-root-ca.crt and openshift-service-ca.crt configmaps
+	counts["Secret"] = namespaces * ns_secrets
+	counts["ConfigMap"] = namespaces * ns_configmaps
+	for _, o := range r.Objects {
+		switch o.Kind {
+		case "Pod":
+			counts["Pod"]++
+		case "Deployment":
+			counts["Deployment"]++
+			counts["Pod"] += deployment_replicas(o.Data)
+		case "Service", "Secret", "ConfigMap":
+			counts[o.Kind]++
+		}
+	}
+	return counts
+}
+
+// Func readme_counts parses the totals table of the README, the counts of each config keyed by its file name
+func readme_counts(data []byte) (map[string]map[string]int, error) {
+	totals := make(map[string]map[string]int)
+	var kinds []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			kinds = nil
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+		if cells[0] == "Config" {
+			kinds = cells[1:]
+			continue
+		}
+		if kinds == nil || strings.HasPrefix(cells[0], "-") {
+			continue
+		}
+		if len(cells) != len(kinds)+1 {
+			return nil, fmt.Errorf("readme totals row %q has %d columns, want %d", line, len(cells), len(kinds)+1)
+		}
+		counts := make(map[string]int)
+		for i, k := range kinds {
+			n, err := strconv.Atoi(cells[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("readme totals row %q has an invalid %s count: %v", line, k, err)
+			}
+			counts[k] = n
+		}
+		totals[cells[0]] = counts
+	}
+	if len(totals) == 0 {
+		return nil, fmt.Errorf("no totals table found in readme")
+	}
+	return totals, nil
+}
+
+// Func deployment_replicas returns spec.replicas of a rendered deployment, 1 when unset
+func deployment_replicas(data []byte) int {
+	var d struct {
+		Spec struct {
+			Replicas *int `yaml:"replicas"`
+		} `yaml:"spec"`
+	}
+	err := yaml.Unmarshal(data, &d)
+	if err != nil || d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// Func observed_count returns the increase of a count metric over the run, peak minus the first sample
+func observed_count(wd string, uuid string, m CountMetric) (int, bool) {
+	files := retrieve_json_files([]string{m.Metric}, uuid)
+	if len(files) == 0 {
+		return 0, false
+	}
+	file := strings.TrimSpace(strings.Split(files[0], "\n")[0])
+	data, err := ioutil.ReadFile(filepath.Join(wd, "collected-metrics", file))
+	if err != nil {
+		log.Println("Problem reading json file", file, "with error", err)
+		return 0, false
+	}
+	var docs []JsonStructLabels
+	err = json.Unmarshal(data, &docs)
+	if err != nil {
+		log.Println("Problem parsing json file", file, "with error", err)
+		return 0, false
+	}
+	if len(docs) == 0 {
+		log.Println("Json file", file, "has no samples")
+		return 0, false
+	}
+
+	// Sum the series of each timestamp, timestamps are RFC3339 so they sort as strings
+	totals := make(map[string]float64)
+	first := ""
+	for _, d := range docs {
+		if m.Phase != "" && d.Labels["phase"] != m.Phase {
+			continue
+		}
+		totals[d.Timestamp] += d.Value
+		if first == "" || d.Timestamp < first {
+			first = d.Timestamp
+		}
+	}
+	peak := totals[first]
+	for _, v := range totals {
+		if v > peak {
+			peak = v
+		}
+	}
+	return int(peak - totals[first]), true
+}
+
+// Func check_counts compares expected counts with the collected metrics of uuid, a mismatch is a difference above tolerance percent
+func check_counts(wd string, uuid string, expected map[string]int, tolerance float64) []CountCheck {
+	var checks []CountCheck
+	for _, m := range count_metrics {
+		c := CountCheck{Kind: m.Kind, Expected: expected[m.Kind]}
+		c.Observed, c.Found = observed_count(wd, uuid, m)
+		diff := float64(c.Observed - c.Expected)
+		if diff < 0 {
+			diff = -diff
+		}
+		c.Match = c.Found && diff <= float64(c.Expected)*tolerance/100
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Func counts_cmd prints the objects a workload is expected to create and, given a uuid, compares them with the collected metrics
+func counts_cmd(args []string) error {
+	fs := flag.NewFlagSet("counts", flag.ExitOnError)
+	var opts RenderOptions
+	fs.StringVar(&opts.Workload, "workload", "", "kube-burner workload config to count")
+	fs.IntVar(&opts.Scale, "scale", env_int("SCALE", 1), "scale factor of the workload")
+	fs.BoolVar(&opts.BFD, "bfd", env_default("BFD", "false") == "true", "enable bfd on the serving pods")
+	fs.IntVar(&opts.QPS, "qps", env_int("QPS", 20), "kube-burner qps")
+	fs.IntVar(&opts.Burst, "burst", env_int("BURST", 20), "kube-burner burst")
+	uuid := fs.String("uuid", "", "uuid of a completed run to compare with its collected metrics")
+	tolerance := fs.Float64("tolerance", 1, "percent difference allowed between expected and observed counts")
+	ns_secrets := fs.Int("ns-secrets", 6, "secrets openshift creates in every new namespace")
+	ns_configmaps := fs.Int("ns-configmaps", 2, "configmaps openshift creates in every new namespace")
+	readme := fs.String("readme", "", "readme whose totals table is compared with the rendered workload")
+	fs.Parse(args)
+
+	if opts.Workload == "" {
+		return fmt.Errorf("please provide the workload config using flag '-workload'")
+	}
+	opts.UUID = *uuid
+	r, err := render_workload(opts)
+	if err != nil {
+		return err
+	}
+	for _, m := range r.Missing {
+		log_fields("object template not found, not counted", "template", m)
+	}
+	expected := expected_counts(r, *ns_secrets, *ns_configmaps)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *readme != "" {
+		return check_readme_counts(w, *readme, opts.Workload, expected_counts(r, 0, 0))
+	}
+	if *uuid == "" {
+		fmt.Fprintln(w, "KIND\tEXPECTED")
+		for _, m := range count_metrics {
+			fmt.Fprintf(w, "%s\t%d\n", m.Kind, expected[m.Kind])
+		}
+		return w.Flush()
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	checks := check_counts(wd, *uuid, expected, *tolerance)
+	mismatches := 0
+	fmt.Fprintln(w, "KIND\tEXPECTED\tOBSERVED\tSTATUS")
+	for _, c := range checks {
+		status := "ok"
+		observed := fmt.Sprint(c.Observed)
+		if !c.Found {
+			status, observed = "missing metric", "-"
+		} else if !c.Match {
+			status = "MISMATCH"
+		}
+		if c.Found && !c.Match {
+			mismatches++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", c.Kind, c.Expected, observed, status)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d object counts do not match the workload", mismatches, len(checks))
+	}
+	return nil
+}
+
+// Func check_readme_counts prints the workload objects next to the readme totals of the config and fails on any difference
+func check_readme_counts(w *tabwriter.Writer, readme string, workload string, rendered map[string]int) error {
+	data, err := ioutil.ReadFile(readme)
+	if err != nil {
+		return err
+	}
+	totals, err := readme_counts(data)
+	if err != nil {
+		return err
+	}
+	documented, ok := totals[filepath.Base(workload)]
+	if !ok {
+		return fmt.Errorf("workload %s is not in the totals table of %s", filepath.Base(workload), readme)
+	}
+	mismatches := 0
+	fmt.Fprintln(w, "KIND\tRENDERED\tREADME\tSTATUS")
+	for _, m := range count_metrics {
+		status := "ok"
+		if rendered[m.Kind] != documented[m.Kind] {
+			status = "MISMATCH"
+			mismatches++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", m.Kind, rendered[m.Kind], documented[m.Kind], status)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d object counts differ from %s, update its totals table", mismatches, len(count_metrics), readme)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadmeCounts(t *testing.T) {
+	data, err := ioutil.ReadFile("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals, err := readme_counts(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every workload config has a row, the delete configs create nothing
+	configs, err := bundle_glob("workload/cfg_*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range configs {
+		name := filepath.Base(config)
+		if strings.HasPrefix(name, "cfg_delete_") {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			documented, ok := totals[name]
+			if !ok {
+				t.Fatalf("%s has no row in the README totals table", name)
+			}
+			r, err := render_workload(RenderOptions{Workload: config, Scale: 1, QPS: 20, Burst: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := expected_counts(r, 0, 0)
+			for _, m := range count_metrics {
+				if rendered[m.Kind] != documented[m.Kind] {
+					t.Errorf("%s: rendered %d, README says %d", m.Kind, rendered[m.Kind], documented[m.Kind])
+				}
+			}
+		})
+	}
+}
+
+func TestExpectedCountsNamespaceObjects(t *testing.T) {
+	r := &Rendered{
+		Namespaces: map[string]string{"served-ns-1": "job-1", "served-ns-2": "job-2", "openshift-sriov-network-operator": "create-networks-job"},
+		Objects: []RenderedObject{
+			{Kind: "Deployment", Data: []byte("spec:\n  replicas: 2\n")},
+			{Kind: "Pod"},
+			{Kind: "Secret"},
+		},
+	}
+	counts := expected_counts(r, 6, 2)
+	want := map[string]int{"Namespace": 2, "Pod": 3, "Deployment": 1, "Secret": 13, "ConfigMap": 4}
+	for k, v := range want {
+		if counts[k] != v {
+			t.Errorf("%s = %d, want %d", k, counts[k], v)
+		}
+	}
+}
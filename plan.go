@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Struct SriovPolicy holds the fields of the sr-iov network node policy the capacity plan depends on
+type SriovPolicy struct {
+	Name         string
+	NumVfs       int
+	ResourceName string
+	NodeSelector map[string]string
+}
+
+// Struct NodeVFs is the number of serving vfs planned on a worker-spk node
+type NodeVFs struct {
+	Node     string
+	Ready    bool
+	Existing bool // already labeled worker-spk before the run
+	VFs      int
+}
+
+// Struct SriovPlan is the worker-spk nodes and vfs a run needs for its scale
+type SriovPlan struct {
+	Scale      int
+	NumVFs     int // vfs of the serving pods, SCALE x vf serving factor
+	NodeVFs    int // numVfs of the policy on every worker-spk node
+	LBCount    int // worker-spk nodes needed, at least 4
+	Existing   []string
+	Label      []string // workers to label worker-spk
+	Allocation []NodeVFs
+}
+
+// Func read_sriov_policy reads the sr-iov network node policy template, leaving the pf placeholder unexpanded
+func read_sriov_policy(file string) (SriovPolicy, error) {
+	data, err := bundle_file(file)
+	if err != nil {
+		return SriovPolicy{}, err
+	}
+	var doc struct {
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+		Spec struct {
+			NumVfs       int               `yaml:"numVfs"`
+			ResourceName string            `yaml:"resourceName"`
+			NodeSelector map[string]string `yaml:"nodeSelector"`
+		} `yaml:"spec"`
+	}
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return SriovPolicy{}, fmt.Errorf("unable to parse %s: %v", file, err)
+	}
+	if doc.Spec.NumVfs <= 0 {
+		return SriovPolicy{}, fmt.Errorf("%s has no spec.numVfs", file)
+	}
+	return SriovPolicy{Name: doc.Metadata.Name, NumVfs: doc.Spec.NumVfs, ResourceName: doc.Spec.ResourceName, NodeSelector: doc.Spec.NodeSelector}, nil
+}
+
+// Func new_sriov_plan picks the worker-spk nodes for a scale like create_icni2_workload.sh and spreads the serving vfs over them
+func new_sriov_plan(nodes []Node, scale int, vf_serving_factor int, policy SriovPolicy, sel NodeSelection, load map[string]int) (*SriovPlan, error) {
+	if _, ok := policy.NodeSelector[worker_spk_label]; !ok {
+		return nil, fmt.Errorf("sr-iov policy %s does not select %s nodes", policy.Name, worker_spk_label)
+	}
+	plan := &SriovPlan{
+		Scale:   scale,
+		NumVFs:  scale * vf_serving_factor,
+		NodeVFs: policy.NumVfs,
+		LBCount: lb_count(scale, vf_serving_factor, policy.NumVfs),
+	}
+
+	var candidates []Node
+	ready := make(map[string]bool)
+	for _, n := range nodes {
+		ready[n.Name] = n.Ready
+		if n.has_role("worker-spk") {
+			plan.Existing = append(plan.Existing, n.Name)
+		}
+		if n.has_role("worker") && !n.has_role("worker-lb") {
+			// A worker that is not ready cannot run the serving pods it would be labeled for
+			if !n.Ready {
+				if !n.has_role("worker-spk") {
+					log_fields("worker is not ready, not labeling it worker-spk", "node", n.Name)
+				}
+				continue
+			}
+			candidates = append(candidates, n)
+		}
+	}
+	spk := append([]string{}, plan.Existing...)
+	if len(spk) < plan.LBCount {
+		ordered, err := sel.order(candidates, load)
+		if err != nil {
+			return plan, err
+		}
+		for _, n := range ordered {
+			if len(spk) == plan.LBCount {
+				break
+			}
+			if !n.has_role("worker-spk") {
+				plan.Label = append(plan.Label, n.Name)
+				spk = append(spk, n.Name)
+			}
+		}
+		if len(spk) < plan.LBCount {
+			return plan, fmt.Errorf("not enough worker nodes to label, need %d worker-spk nodes but only %d are available with node selection %s, excluding worker-lb and workers that are not ready", plan.LBCount, len(spk), sel.Policy)
+		}
+	}
+	sort.Strings(spk)
+
+	// Every worker-spk node gets the policy vfs, LBCount nodes hold the serving vfs so they are spread within numVfs
+	for i, node := range spk {
+		vfs := plan.NumVFs / len(spk)
+		if i < plan.NumVFs%len(spk) {
+			vfs++
+		}
+		plan.Allocation = append(plan.Allocation, NodeVFs{Node: node, Ready: ready[node], Existing: exists(plan.Existing, node), VFs: vfs})
+	}
+	return plan, nil
+}
+
+// Func print writes the plan as a table of worker-spk nodes
+func (p *SriovPlan) print() error {
+	fmt.Printf("SCALE %d needs %d vfs on %d worker-spk nodes with %d vfs each, %d to label\n", p.Scale, p.NumVFs, p.LBCount, p.NodeVFs, len(p.Label))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tWORKER-SPK\tREADY\tVFS\tFREE")
+	for _, a := range p.Allocation {
+		label := "label"
+		if a.Existing {
+			label = "existing"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%d\n", a.Node, label, a.Ready, a.VFs, p.NodeVFs-a.VFs)
+	}
+	return w.Flush()
+}
+
+// Func plan_cmd checks the sr-iov capacity of a scale against the policy and the nodes without changing the cluster
+func plan_cmd(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	scale := fs.Int("scale", env_int("SCALE", 1), "scale factor of the workload")
+	factor := fs.Int("vf-serving-factor", 140, "vfs needed per unit of scale")
+	policy_file := fs.String("sriov-policy", "workload/sriov_policy.yaml", "sr-iov network node policy template")
+	nodes_file := fs.String("nodes", "", "output of oc get nodes -o json used instead of the cluster")
+	kubeconfig := fs.String("kubeconfig", env_default("KUBECONFIG", "/home/kni/clusterconfigs/auth/kubeconfig"), "kubeconfig of the cluster")
+	var sel NodeSelection
+	node_selection_flags(fs, &sel)
+	fs.Parse(args)
+
+	err := sel.validate()
+	if err != nil {
+		return err
+	}
+	policy, err := read_sriov_policy(*policy_file)
+	if err != nil {
+		return err
+	}
+	cluster := &OcCluster{Kubeconfig: *kubeconfig}
+	var nodes []Node
+	var load map[string]int
+	if *nodes_file != "" {
+		if sel.Policy == "least-loaded" {
+			return fmt.Errorf("node selection least-loaded reads pods from the cluster and cannot be used with flag 'nodes'")
+		}
+		data, err := os.ReadFile(*nodes_file)
+		if err != nil {
+			return err
+		}
+		nodes, err = parse_nodes(data)
+		if err != nil {
+			return err
+		}
+	} else {
+		nodes, err = cluster.Nodes(context.Background())
+		if err != nil {
+			return err
+		}
+		if sel.Policy == "least-loaded" {
+			load, err = cluster.NodePods(context.Background())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	plan, err := new_sriov_plan(nodes, *scale, *factor, policy, sel, load)
+	if err != nil {
+		return err
+	}
+	err = plan.print()
+	if err != nil {
+		return err
+	}
+	for _, a := range plan.Allocation {
+		if !a.Ready {
+			log_fields("worker-spk node is not ready", "node", a.Node)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLBCount(t *testing.T) {
+	tests := []struct {
+		scale, factor, vfs int
+		want               int
+	}{
+		{scale: 1, factor: 140, vfs: 64, want: 4},
+		{scale: 2, factor: 140, vfs: 64, want: 5},
+		{scale: 4, factor: 140, vfs: 64, want: 9},
+		{scale: 8, factor: 140, vfs: 64, want: 18},
+		{scale: 1, factor: 64, vfs: 64, want: 4},
+		{scale: 5, factor: 64, vfs: 64, want: 5},
+		{scale: 1, factor: 140, vfs: 128, want: 4},
+	}
+	for _, tt := range tests {
+		if got := lb_count(tt.scale, tt.factor, tt.vfs); got != tt.want {
+			t.Errorf("lb_count(%d, %d, %d) = %d, want %d", tt.scale, tt.factor, tt.vfs, got, tt.want)
+		}
+	}
+}
+
+func TestNewSriovPlan(t *testing.T) {
+	policy := SriovPolicy{Name: "serving-lb-policy", NumVfs: 64, NodeSelector: map[string]string{worker_spk_label: ""}}
+	not_ready := spk_nodes(6, 0)
+	not_ready[1].Ready = false
+	lb := spk_nodes(6, 0)
+	lb[0].Roles = append(lb[0].Roles, "worker-lb")
+	racks := spk_nodes(8, 0)
+	for i := range racks {
+		racks[i].Labels[default_rack_label] = []string{"r1", "r2"}[i%2]
+	}
+
+	tests := []struct {
+		name   string
+		nodes  []Node
+		scale  int
+		sel    NodeSelection
+		policy SriovPolicy
+		load   map[string]int
+		label  []string
+		vfs    []int
+		err    string
+	}{
+		{name: "labels the first workers", nodes: spk_nodes(6, 0), scale: 1,
+			label: []string{"worker-a", "worker-b", "worker-c", "worker-d"}, vfs: []int{35, 35, 35, 35}},
+		{name: "keeps existing worker-spk nodes", nodes: spk_nodes(6, 2), scale: 2,
+			label: []string{"worker-c", "worker-d", "worker-e"}, vfs: []int{56, 56, 56, 56, 56}},
+		{name: "enough existing worker-spk nodes", nodes: spk_nodes(6, 5), scale: 1,
+			vfs: []int{28, 28, 28, 28, 28}},
+		{name: "skips workers that are not ready", nodes: not_ready, scale: 1,
+			label: []string{"worker-a", "worker-c", "worker-d", "worker-e"}},
+		{name: "skips worker-lb", nodes: lb, scale: 1,
+			label: []string{"worker-b", "worker-c", "worker-d", "worker-e"}},
+		{name: "least loaded", nodes: spk_nodes(6, 0), scale: 1, sel: NodeSelection{Policy: "least-loaded"},
+			load:  map[string]int{"worker-a": 50, "worker-b": 10, "worker-c": 40, "worker-d": 0, "worker-e": 30, "worker-f": 20},
+			label: []string{"worker-d", "worker-b", "worker-f", "worker-e"}},
+		{name: "rack", nodes: racks, scale: 1, sel: NodeSelection{Policy: "rack", Rack: "r2", RackLabel: default_rack_label},
+			label: []string{"worker-b", "worker-d", "worker-f", "worker-h"}},
+		{name: "uneven vfs", nodes: spk_nodes(6, 6), scale: 1,
+			vfs: []int{24, 24, 23, 23, 23, 23}},
+		{name: "not enough workers", nodes: spk_nodes(6, 0), scale: 4, err: "need 9 worker-spk nodes but only 6 are available"},
+		{name: "not enough ready workers", nodes: not_ready[:4], scale: 1, err: "need 4 worker-spk nodes but only 3 are available"},
+		{name: "policy without worker-spk selector", nodes: spk_nodes(6, 0), scale: 1,
+			policy: SriovPolicy{Name: "other", NumVfs: 64}, err: "does not select"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			if tt.policy.Name != "" {
+				p = tt.policy
+			}
+			sel := tt.sel
+			if sel.Policy == "" {
+				sel.Policy = "first"
+			}
+			plan, err := new_sriov_plan(tt.nodes, tt.scale, 140, p, sel, tt.load)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(plan.Label, ",") != strings.Join(tt.label, ",") {
+				t.Errorf("labels %v, want %v", plan.Label, tt.label)
+			}
+			total := 0
+			for i, a := range plan.Allocation {
+				total += a.VFs
+				if a.VFs > p.NumVfs {
+					t.Errorf("%s gets %d vfs, above numVfs %d", a.Node, a.VFs, p.NumVfs)
+				}
+				if tt.vfs != nil && (i >= len(tt.vfs) || a.VFs != tt.vfs[i]) {
+					t.Errorf("allocation %v, want vfs %v", plan.Allocation, tt.vfs)
+					break
+				}
+			}
+			if total != plan.NumVFs {
+				t.Errorf("allocated %d vfs, want %d", total, plan.NumVFs)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Struct OperatorRef is an operator whose cluster service version must have succeeded
+type OperatorRef struct {
+	Namespace string
+	Prefix    string // name of the csv without its version
+}
+
+// Operators a run needs installed
+var preflight_operators = []OperatorRef{
+	{Namespace: "openshift-sriov-network-operator", Prefix: "sriov-network-operator"},
+}
+
+// Struct CheckResult is the outcome of one preflight check
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pass, warn or fail
+	Detail string `json:"detail"`
+}
+
+// Struct PreflightCheck is a named readiness check of the cluster
+type PreflightCheck struct {
+	Name string
+	Run  func(ctx context.Context, cluster Cluster) CheckResult
+}
+
+// Func check_pass returns a passing result
+func check_pass(detail string, a ...interface{}) CheckResult {
+	return CheckResult{Status: "pass", Detail: fmt.Sprintf(detail, a...)}
+}
+
+// Func check_warn returns a result that does not fail the preflight
+func check_warn(detail string, a ...interface{}) CheckResult {
+	return CheckResult{Status: "warn", Detail: fmt.Sprintf(detail, a...)}
+}
+
+// Func check_fail returns a failing result
+func check_fail(detail string, a ...interface{}) CheckResult {
+	return CheckResult{Status: "fail", Detail: fmt.Sprintf(detail, a...)}
+}
+
+// Func short_list joins names, keeping the first few
+func short_list(names []string) string {
+	if len(names) > 5 {
+		return strings.Join(names[:5], ", ") + fmt.Sprintf(" and %d more", len(names)-5)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Func preflight_checks returns the checks run before a workload, leftover namespaces of uuid are ignored
+func preflight_checks(uuid string) []PreflightCheck {
+	return []PreflightCheck{
+		{Name: "nodes-ready", Run: check_nodes_ready},
+		{Name: "mcp-status", Run: check_mcp_status},
+		{Name: "operator-csvs", Run: check_operator_csvs},
+		{Name: "sriov-pf", Run: check_sriov_pf},
+		{Name: "leftover-namespaces", Run: func(ctx context.Context, cluster Cluster) CheckResult {
+			return check_leftover_namespaces(ctx, cluster, uuid)
+		}},
+	}
+}
+
+// Func check_nodes_ready fails when any node is NotReady
+func check_nodes_ready(ctx context.Context, cluster Cluster) CheckResult {
+	nodes, err := cluster.Nodes(ctx)
+	if err != nil {
+		return check_fail("%v", err)
+	}
+	var not_ready []string
+	for _, n := range nodes {
+		if !n.Ready {
+			not_ready = append(not_ready, n.Name)
+		}
+	}
+	if len(not_ready) > 0 {
+		return check_fail("%d of %d nodes not ready: %s", len(not_ready), len(nodes), short_list(not_ready))
+	}
+	return check_pass("%d nodes ready", len(nodes))
+}
+
+// Func check_mcp_status fails when a machine config pool is degraded or not updated
+func check_mcp_status(ctx context.Context, cluster Cluster) CheckResult {
+	pools, err := cluster.MachineConfigPools(ctx)
+	if err != nil {
+		return check_fail("%v", err)
+	}
+	var problems []string
+	for _, p := range pools {
+		switch {
+		case p.Degraded:
+			problems = append(problems, p.Name+" degraded")
+		case p.Updating:
+			problems = append(problems, p.Name+" updating")
+		case !p.Updated:
+			problems = append(problems, p.Name+" not updated")
+		}
+	}
+	if len(problems) > 0 {
+		return check_fail("%s", short_list(problems))
+	}
+	return check_pass("%d pools updated", len(pools))
+}
+
+// Func check_operator_csvs fails when a required operator has no succeeded cluster service version
+func check_operator_csvs(ctx context.Context, cluster Cluster) CheckResult {
+	var found []string
+	for _, op := range preflight_operators {
+		csvs, err := cluster.OperatorCSVs(ctx, op.Namespace)
+		if err != nil {
+			return check_fail("%v", err)
+		}
+		phase := ""
+		for _, c := range csvs {
+			if strings.HasPrefix(c.Name, op.Prefix) {
+				phase = c.Phase
+				if phase == "Succeeded" {
+					found = append(found, c.Name)
+					break
+				}
+			}
+		}
+		if phase == "" {
+			return check_fail("%s not installed in %s", op.Prefix, op.Namespace)
+		}
+		if phase != "Succeeded" {
+			return check_fail("%s in %s is %s", op.Prefix, op.Namespace, phase)
+		}
+	}
+	return check_pass("%s", strings.Join(found, ", "))
+}
+
+// Func check_sriov_pf fails when the worker-spk nodes do not share the same pf
+func check_sriov_pf(ctx context.Context, cluster Cluster) CheckResult {
+	nodes, err := cluster.Nodes(ctx)
+	if err != nil {
+		return check_fail("%v", err)
+	}
+	pfs := make(map[string][]string)
+	for _, n := range nodes {
+		if !n.has_role("worker-spk") {
+			continue
+		}
+		pf, err := cluster.SriovPF(ctx, n.Name)
+		if err != nil {
+			return check_fail("%v", err)
+		}
+		pfs[pf] = append(pfs[pf], n.Name)
+	}
+	if len(pfs) == 0 {
+		return check_warn("no worker-spk nodes yet, the pf is checked once run labels them")
+	}
+	var found []string
+	for pf, names := range pfs {
+		found = append(found, fmt.Sprintf("%s on %s", pf, short_list(names)))
+	}
+	sort.Strings(found)
+	if len(pfs) > 1 {
+		return check_fail("worker-spk nodes use different pfs: %s", strings.Join(found, "; "))
+	}
+	return check_pass("%s", found[0])
+}
+
+// Func check_leftover_namespaces fails when namespaces created by kube-burner for another uuid remain
+func check_leftover_namespaces(ctx context.Context, cluster Cluster, uuid string) CheckResult {
+	namespaces, err := cluster.Namespaces(ctx, "kube-burner-uuid")
+	if err != nil {
+		return check_fail("%v", err)
+	}
+	by_uuid := make(map[string]int)
+	for _, ns := range namespaces {
+		if u := ns.Labels["kube-burner-uuid"]; u != uuid {
+			by_uuid[u]++
+		}
+	}
+	if len(by_uuid) == 0 {
+		return check_pass("no namespaces from previous runs")
+	}
+	var found []string
+	for u, n := range by_uuid {
+		found = append(found, fmt.Sprintf("%s (%d)", u, n))
+	}
+	sort.Strings(found)
+	return check_fail("namespaces of uuids %s remain, remove them with cleanup -uuid <uuid> -namespaces", short_list(found))
+}
+
+// Func run_preflight runs every check, each with its own timeout
+func run_preflight(ctx context.Context, cluster Cluster, checks []PreflightCheck, timeout time.Duration) []CheckResult {
+	var results []CheckResult
+	for _, c := range checks {
+		check_ctx, cancel := context.WithTimeout(ctx, timeout)
+		r := c.Run(check_ctx, cluster)
+		cancel()
+		r.Name = c.Name
+		results = append(results, r)
+	}
+	return results
+}
+
+// Func preflight_cmd checks the cluster is ready for a run and prints a pass/fail table or json
+func preflight_cmd(args []string) error {
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	kubeconfig := fs.String("kubeconfig", env_default("KUBECONFIG", "/home/kni/clusterconfigs/auth/kubeconfig"), "kubeconfig of the cluster")
+	ssh_key := fs.String("ssh-key", "/home/kni/.ssh/id_rsa", "ssh key used to read the sr-iov pf from the worker-spk nodes")
+	uuid := fs.String("uuid", os.Getenv("UUID"), "uuid of the run, its own namespaces are not reported as leftovers")
+	timeout := fs.Duration("timeout", 2*time.Minute, "timeout of each check")
+	skip := fs.String("skip", "", "comma separated checks to skip, e.g. sriov-pf")
+	as_json := fs.Bool("json", false, "print results as json")
+	fs.Parse(args)
+
+	var checks []PreflightCheck
+	for _, c := range preflight_checks(*uuid) {
+		if !exists(strings.Split(*skip, ","), c.Name) {
+			checks = append(checks, c)
+		}
+	}
+	cluster := &OcCluster{Kubeconfig: *kubeconfig, SSHKey: *ssh_key}
+	results := run_preflight(context.Background(), cluster, checks, *timeout)
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == "fail" {
+			failed++
+		}
+	}
+	if *as_json {
+		out, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, strings.ToUpper(r.Status), r.Detail)
+		}
+		err := w.Flush()
+		if err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d preflight checks failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Struct CheckCase is a cluster a preflight check should give status with detail for
+type CheckCase struct {
+	name    string
+	cluster *FakeCluster
+	status  string
+	detail  string
+}
+
+// Func run_check_cases runs a preflight check against every case
+func run_check_cases(t *testing.T, check func(ctx context.Context, cluster Cluster) CheckResult, tests []CheckCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := check(context.Background(), tt.cluster)
+			if r.Status != tt.status || !strings.Contains(r.Detail, tt.detail) {
+				t.Errorf("got %s %q, want %s containing %q", r.Status, r.Detail, tt.status, tt.detail)
+			}
+		})
+	}
+}
+
+func TestCheckNodesReady(t *testing.T) {
+	not_ready := spk_nodes(3, 0)
+	not_ready[1].Ready = false
+	run_check_cases(t, check_nodes_ready, []CheckCase{
+		{"ready", &FakeCluster{Nodes_: spk_nodes(3, 0)}, "pass", "3 nodes ready"},
+		{"not ready", &FakeCluster{Nodes_: not_ready}, "fail", "1 of 3 nodes not ready: worker-b"},
+		{"error", &FakeCluster{Errors: map[string]error{"Nodes": errors.New("connection refused")}}, "fail", "connection refused"},
+	})
+}
+
+func TestCheckMCPStatus(t *testing.T) {
+	updated := []MachineConfigPool{{Name: "master", Updated: true}, {Name: "worker", Updated: true}}
+	run_check_cases(t, check_mcp_status, []CheckCase{
+		{"updated", &FakeCluster{Pools: updated}, "pass", "2 pools updated"},
+		{"degraded", &FakeCluster{Pools: []MachineConfigPool{updated[0], {Name: "worker", Updated: true, Degraded: true}}}, "fail", "worker degraded"},
+		{"updating", &FakeCluster{Pools: []MachineConfigPool{updated[0], {Name: "worker-spk", Updating: true}}}, "fail", "worker-spk updating"},
+		{"not updated", &FakeCluster{Pools: []MachineConfigPool{{Name: "master"}, updated[1]}}, "fail", "master not updated"},
+		{"error", &FakeCluster{Errors: map[string]error{"MachineConfigPools": errors.New("forbidden")}}, "fail", "forbidden"},
+	})
+}
+
+func TestCheckOperatorCSVs(t *testing.T) {
+	ns := preflight_operators[0].Namespace
+	run_check_cases(t, check_operator_csvs, []CheckCase{
+		{"succeeded", &FakeCluster{CSVs: map[string][]OperatorCSV{ns: {{Name: "sriov-network-operator.4.10.0", Phase: "Succeeded"}}}}, "pass", "sriov-network-operator.4.10.0"},
+		{"upgrade in progress", &FakeCluster{CSVs: map[string][]OperatorCSV{ns: {{Name: "sriov-network-operator.4.10.0", Phase: "Replacing"}, {Name: "sriov-network-operator.4.11.0", Phase: "Succeeded"}}}}, "pass", "sriov-network-operator.4.11.0"},
+		{"installing", &FakeCluster{CSVs: map[string][]OperatorCSV{ns: {{Name: "sriov-network-operator.4.10.0", Phase: "Installing"}}}}, "fail", "sriov-network-operator in openshift-sriov-network-operator is Installing"},
+		{"failed", &FakeCluster{CSVs: map[string][]OperatorCSV{ns: {{Name: "sriov-network-operator.4.10.0", Phase: "Failed"}}}}, "fail", "is Failed"},
+		{"other operator only", &FakeCluster{CSVs: map[string][]OperatorCSV{ns: {{Name: "metallb-operator.4.10.0", Phase: "Succeeded"}}}}, "fail", "sriov-network-operator not installed"},
+		{"not installed", &FakeCluster{}, "fail", "not installed in openshift-sriov-network-operator"},
+		{"error", &FakeCluster{Errors: map[string]error{"OperatorCSVs": errors.New("timeout")}}, "fail", "timeout"},
+	})
+}
+
+func TestCheckSriovPF(t *testing.T) {
+	run_check_cases(t, check_sriov_pf, []CheckCase{
+		{"same pf", &FakeCluster{Nodes_: spk_nodes(6, 4)}, "pass", "ens1f0 on worker-a, worker-b, worker-c, worker-d"},
+		{"no worker-spk", &FakeCluster{Nodes_: spk_nodes(6, 0)}, "warn", "no worker-spk nodes yet"},
+		{"mixed pfs", &FakeCluster{Nodes_: spk_nodes(6, 4), PFs: map[string]string{"worker-c": "ens2f1"}}, "fail", "worker-spk nodes use different pfs: ens1f0 on worker-a, worker-b, worker-d; ens2f1 on worker-c"},
+		{"pf error", &FakeCluster{Nodes_: spk_nodes(6, 4), Errors: map[string]error{"SriovPF": errors.New("ssh: connection refused")}}, "fail", "ssh: connection refused"},
+	})
+}
+
+func TestCheckLeftoverNamespaces(t *testing.T) {
+	labeled := func(name string, uuid string) Namespace {
+		return Namespace{Name: name, Labels: map[string]string{"kube-burner-uuid": uuid}}
+	}
+	check := func(ctx context.Context, cluster Cluster) CheckResult {
+		return check_leftover_namespaces(ctx, cluster, "u1")
+	}
+	run_check_cases(t, check, []CheckCase{
+		{"none", &FakeCluster{}, "pass", "no namespaces from previous runs"},
+		{"own run", &FakeCluster{NS: []Namespace{labeled("served-ns-1", "u1")}}, "pass", "no namespaces"},
+		{"earlier runs", &FakeCluster{NS: []Namespace{labeled("served-ns-1", "u1"), labeled("served-ns-1", "u0"), labeled("served-ns-2", "u0"), labeled("serving-ns-1", "s0")}}, "fail", "namespaces of uuids s0 (1), u0 (2) remain"},
+	})
+}
+
+func TestRunPreflight(t *testing.T) {
+	cluster := &FakeCluster{Nodes_: spk_nodes(4, 0), Block: map[string]bool{"MachineConfigPools": true}}
+	results := run_preflight(context.Background(), cluster, preflight_checks("u1"), 10*time.Millisecond)
+	want := map[string]string{"nodes-ready": "pass", "mcp-status": "fail", "operator-csvs": "fail", "sriov-pf": "warn", "leftover-namespaces": "pass"}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for _, r := range results {
+		if want[r.Name] != r.Status {
+			t.Errorf("check %s is %s (%s), want %s", r.Name, r.Status, r.Detail, want[r.Name])
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Struct RunRecord is a run entry in the local registry
+type RunRecord struct {
+	UUID              string     `json:"uuid"`
+	ServingUUID       string     `json:"servingUUID"`
+	Workload          string     `json:"workload"`
+	Scale             int        `json:"scale"`
+	BFD               bool       `json:"bfd"`
+	QPS               int        `json:"qps"`
+	Burst             int        `json:"burst"`
+	KubeBurnerRelease string     `json:"kubeBurnerRelease"`
+	KubeBurnerVersion string     `json:"kubeBurnerVersion,omitempty"`
+	Start             time.Time  `json:"start"`
+	End               *time.Time `json:"end,omitempty"` // nil while the run is still running
+	Status            string     `json:"status"`
+	Error             string     `json:"error,omitempty"`
+
+	BFDSessions []BFDNamespace    `json:"bfdSessions,omitempty"` // bfd session health per serving namespace after the serving init job
+	KubeBurner  []PhaseKubeBurner `json:"kubeBurner,omitempty"`  // exit status and job summaries of each kube-burner invocation
+}
+
+// Func registry_file returns the path of the run registry
+func registry_file() string {
+	return filepath.Join(runs_dir, "registry.json")
+}
+
+// Func new_uuid generates a random version 4 uuid
+func new_uuid() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Func load_registry reads all runs from the registry, returning none if it does not exist yet
+func load_registry() ([]RunRecord, error) {
+	var records []RunRecord
+	data, err := os.ReadFile(registry_file())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// How long to wait for the registry lock held by another run, a lock older than this is left by a killed process
+const registry_lock_timeout = 30 * time.Second
+
+// Func lock_registry takes an exclusive lock file next to the registry and returns the func releasing it
+func lock_registry() (func(), error) {
+	err := os.MkdirAll(runs_dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	lock := registry_file() + ".lock"
+	deadline := time.Now().Add(registry_lock_timeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintln(f, os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, serr := os.Stat(lock); serr == nil && time.Since(info.ModTime()) > registry_lock_timeout {
+			log_fields("removing stale registry lock", "lock", lock, "age", time.Since(info.ModTime()).Round(time.Second))
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("run registry is locked by another run, remove %s if no run is active", lock)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Func record_run adds or replaces the registry entry with the same uuid, holding the registry lock so concurrent runs keep each other's entries
+func record_run(r RunRecord) error {
+	unlock, err := lock_registry()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := load_registry()
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range records {
+		if records[i].UUID == r.UUID {
+			records[i] = r
+			found = true
+		}
+	}
+	if !found {
+		records = append(records, r)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(runs_dir, "registry-*.json")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), registry_file())
+}
+
+// Func list_runs_cmd prints the runs recorded in the registry
+func list_runs_cmd(args []string) error {
+	fs := flag.NewFlagSet("list-runs", flag.ExitOnError)
+	workload := fs.String("workload", "", "only list runs whose workload config contains this string")
+	as_json := fs.Bool("json", false, "print runs as json")
+	fs.Parse(args)
+
+	records, err := load_registry()
+	if err != nil {
+		return err
+	}
+	var runs []RunRecord
+	for _, r := range records {
+		if strings.Contains(r.Workload, *workload) {
+			runs = append(runs, r)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+
+	if *as_json {
+		out, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "UUID\tWORKLOAD\tSCALE\tBFD\tQPS\tBURST\tKUBE-BURNER\tSTART\tEND\tSTATUS")
+	for _, r := range runs {
+		end := ""
+		if r.End != nil {
+			end = r.End.Format(time.RFC3339)
+		}
+		version := r.KubeBurnerVersion
+		if version == "" {
+			version = r.KubeBurnerRelease
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%t\t%d\t%d\t%s\t%s\t%s\t%s\n", r.UUID, r.Workload, r.Scale, r.BFD, r.QPS, r.Burst, version, r.Start.Format(time.RFC3339), end, r.Status)
+	}
+	return w.Flush()
+}
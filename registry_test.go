@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordRunConcurrent(t *testing.T) {
+	in_temp_dir(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- record_run(RunRecord{UUID: fmt.Sprintf("run-%d", i), Start: time.Now(), Status: "running"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := load_registry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 20 {
+		t.Errorf("registry has %d records, want 20 from the concurrent runs", len(records))
+	}
+	if _, err := os.Stat(registry_file() + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("registry lock left behind: %v", err)
+	}
+}
+
+func TestRecordRunStaleLock(t *testing.T) {
+	in_temp_dir(t)
+	os.MkdirAll(runs_dir, 0755)
+	lock := registry_file() + ".lock"
+	err := os.WriteFile(lock, []byte("1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * registry_lock_timeout)
+	os.Chtimes(lock, old, old)
+
+	err = record_run(RunRecord{UUID: "run-1", Status: "running"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunRecordEndOmitted(t *testing.T) {
+	data, err := json.Marshal(RunRecord{UUID: "run-1", Status: "running"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"end"`) {
+		t.Errorf("running record has an end time: %s", data)
+	}
+
+	end := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	data, err = json.Marshal(RunRecord{UUID: "run-1", Status: "completed", End: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"end":"2026-10-19T10:00:00Z"`) {
+		t.Errorf("completed record has no end time: %s", data)
+	}
+}
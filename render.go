@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template functions kube-burner provides to workload configs and object templates
+var kube_burner_funcs = template.FuncMap{
+	"add": func(i ...interface{}) int {
+		sum := 0
+		for _, v := range i {
+			sum += to_int(v)
+		}
+		return sum
+	},
+	"multiply": func(a interface{}, b interface{}) int {
+		return to_int(a) * to_int(b)
+	},
+	"sequence": func(start interface{}, end interface{}) []int {
+		var seq []int
+		for i := to_int(start); i <= to_int(end); i++ {
+			seq = append(seq, i)
+		}
+		return seq
+	},
+	// Same argument order as sprig, contains substr str
+	"contains": func(substr string, s string) bool {
+		return strings.Contains(s, substr)
+	},
+	// Sprig fail, aborts rendering with msg
+	"fail": func(msg string) (string, error) {
+		return "", errors.New(msg)
+	},
+}
+
+// Func to_int converts a template value, such as an env var string, to an int
+func to_int(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(strings.TrimSpace(n))
+		return i
+	}
+	return 0
+}
+
+// Struct WorkloadObject is an object entry of a kube-burner job
+type WorkloadObject struct {
+	ObjectTemplate string                 `yaml:"objectTemplate"`
+	Replicas       int                    `yaml:"replicas"`
+	InputVars      map[string]interface{} `yaml:"inputVars"`
+	Kind           string                 `yaml:"kind"`
+}
+
+// Struct WorkloadJob is a job of a rendered kube-burner config
+type WorkloadJob struct {
+	Name                 string           `yaml:"name"`
+	JobType              string           `yaml:"jobType"`
+	JobIterations        int              `yaml:"jobIterations"`
+	Namespace            string           `yaml:"namespace"`
+	NamespacedIterations bool             `yaml:"namespacedIterations"`
+	Objects              []WorkloadObject `yaml:"objects"`
+}
+
+// Struct WorkloadConfig is the part of a kube-burner config used to render its objects
+type WorkloadConfig struct {
+	Jobs []WorkloadJob `yaml:"jobs"`
+}
+
+// Struct RenderedObject is one object created by a job iteration and replica
+type RenderedObject struct {
+	Job        string
+	Iteration  int
+	Replica    int
+	Template   string
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+	Data       []byte
+}
+
+// Struct Rendered is the expanded config and the objects it creates
+type Rendered struct {
+	Config     []byte
+	Workload   WorkloadConfig
+	Objects    []RenderedObject
+	Namespaces map[string]string // namespaces created by create jobs, keyed by name with the job creating them
+	Missing    []string          // object templates not found, e.g. generated at run time
+}
+
+// Struct RenderOptions holds the values a workload config is rendered with
+type RenderOptions struct {
+	Workload   string
+	Scale      int
+	QPS        int
+	Burst      int
+	BFD        bool
+	Iterations int // overrides jobIterations of every create job when set
+	UUID       string
+	Strict     bool // fail on fields missing from the object template data instead of rendering them empty
+	IPPlan     *IPPlan
+}
+
+// Func missing_key returns the text/template missingkey option for the options
+func (o RenderOptions) missing_key() string {
+	if o.Strict {
+		return "missingkey=error"
+	}
+	return "missingkey=zero"
+}
+
+// Func env returns the variables the config is rendered with, the environment plus the workload parameters like kube-burner
+func (o RenderOptions) env() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	env["SCALE"] = strconv.Itoa(o.Scale)
+	env["QPS"] = strconv.Itoa(o.QPS)
+	env["BURST"] = strconv.Itoa(o.Burst)
+	env["BFD"] = strconv.FormatBool(o.BFD)
+	if o.IPPlan != nil {
+		for _, kv := range o.IPPlan.env() {
+			i := strings.Index(kv, "=")
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	return env
+}
+
+// Func job_namespace returns the namespace a job creates for an iteration
+func job_namespace(job WorkloadJob, iteration int) string {
+	if job.NamespacedIterations {
+		return fmt.Sprintf("%s-%d", job.Namespace, iteration)
+	}
+	return job.Namespace
+}
+
+// Func render_config evaluates a workload config template and parses its jobs
+func render_config(opts RenderOptions) ([]byte, WorkloadConfig, error) {
+	var cfg WorkloadConfig
+	data, err := bundle_file(opts.Workload)
+	if err != nil {
+		return nil, cfg, err
+	}
+	// Env vars are optional in configs, so unset ones always render empty
+	t, err := template.New(filepath.Base(opts.Workload)).Funcs(kube_burner_funcs).Option("missingkey=zero").Parse(string(data))
+	if err != nil {
+		return nil, cfg, err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, opts.env())
+	if err != nil {
+		return nil, cfg, err
+	}
+	err = yaml.Unmarshal(buf.Bytes(), &cfg)
+	if err != nil {
+		return buf.Bytes(), cfg, fmt.Errorf("rendered %s is not valid yaml: %v", opts.Workload, err)
+	}
+	return buf.Bytes(), cfg, nil
+}
+
+// Func render_workload expands a workload config and every object its create jobs make
+func render_workload(opts RenderOptions) (*Rendered, error) {
+	config, cfg, err := render_config(opts)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rendered{Config: config, Workload: cfg, Namespaces: make(map[string]string)}
+	templates := make(map[string]*template.Template)
+
+	for _, job := range cfg.Jobs {
+		if job.JobType != "" && job.JobType != "create" {
+			continue
+		}
+		iterations := job.JobIterations
+		if opts.Iterations > 0 {
+			iterations = opts.Iterations
+		}
+		for i := 1; i <= iterations; i++ {
+			ns := job_namespace(job, i)
+			if _, ok := r.Namespaces[ns]; !ok {
+				r.Namespaces[ns] = job.Name
+			}
+			for _, o := range job.Objects {
+				path := strings.TrimSpace(o.ObjectTemplate)
+				t, ok := templates[path]
+				if !ok {
+					data, err := bundle_file(path)
+					if err != nil {
+						if os.IsNotExist(err) {
+							if !exists(r.Missing, path) {
+								r.Missing = append(r.Missing, path)
+							}
+							continue
+						}
+						return nil, err
+					}
+					t, err = template.New(filepath.Base(path)).Funcs(kube_burner_funcs).Option(opts.missing_key()).Parse(string(data))
+					if err != nil {
+						return nil, fmt.Errorf("job %s: %v", job.Name, err)
+					}
+					templates[path] = t
+				}
+				for rep := 1; rep <= o.Replicas; rep++ {
+					obj, err := render_object(t, job, o, i, rep, opts.UUID)
+					if err != nil {
+						return nil, err
+					}
+					obj.Template = path
+					if obj.Namespace == "" {
+						obj.Namespace = ns
+					}
+					r.Objects = append(r.Objects, obj)
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// Func render_object evaluates an object template for one iteration and replica
+func render_object(t *template.Template, job WorkloadJob, o WorkloadObject, iteration int, replica int, uuid string) (RenderedObject, error) {
+	obj := RenderedObject{Job: job.Name, Iteration: iteration, Replica: replica}
+	data := map[string]interface{}{
+		"JobName":   job.Name,
+		"Iteration": iteration,
+		"Replica":   replica,
+		"UUID":      uuid,
+	}
+	for k, v := range o.InputVars {
+		data[k] = v
+	}
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if err != nil {
+		return obj, fmt.Errorf("job %s iteration %d replica %d: %v", job.Name, iteration, replica, err)
+	}
+	var meta struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+	}
+	err = yaml.Unmarshal(buf.Bytes(), &meta)
+	if err != nil {
+		return obj, fmt.Errorf("job %s iteration %d replica %d: %s is not valid yaml: %v", job.Name, iteration, replica, t.Name(), err)
+	}
+	obj.APIVersion = meta.APIVersion
+	obj.Kind = meta.Kind
+	obj.Name = meta.Metadata.Name
+	obj.Namespace = meta.Metadata.Namespace
+	obj.Data = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(buf.Bytes()), []byte("---")))
+	return obj, nil
+}
+
+// Func write writes the expanded config and the objects of each job to dir
+func (r *Rendered) write(dir string) error {
+	err := os.MkdirAll(filepath.Join(dir, "objects"), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, "config.yml"), r.Config, 0644)
+	if err != nil {
+		return err
+	}
+	jobs := make(map[string]*bytes.Buffer)
+	var order []string
+	for _, o := range r.Objects {
+		buf, ok := jobs[o.Job]
+		if !ok {
+			buf = &bytes.Buffer{}
+			jobs[o.Job] = buf
+			order = append(order, o.Job)
+		}
+		fmt.Fprintf(buf, "---\n# iteration %d replica %d from %s\n", o.Iteration, o.Replica, o.Template)
+		buf.Write(o.Data)
+		buf.WriteString("\n")
+	}
+	for _, job := range order {
+		err = os.WriteFile(filepath.Join(dir, "objects", job+".yml"), jobs[job].Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Func kind_totals counts rendered objects per kind
+func (r *Rendered) kind_totals() map[string]int {
+	totals := make(map[string]int)
+	for _, o := range r.Objects {
+		totals[o.Kind]++
+	}
+	return totals
+}
+
+// Func namespace_totals counts rendered objects per namespace and kind
+func (r *Rendered) namespace_totals() map[string]map[string]int {
+	totals := make(map[string]map[string]int)
+	for _, o := range r.Objects {
+		if totals[o.Namespace] == nil {
+			totals[o.Namespace] = make(map[string]int)
+		}
+		totals[o.Namespace][o.Kind]++
+	}
+	return totals
+}
+
+// Func render_cmd writes the fully expanded workload config and objects and prints totals per kind and namespace
+func render_cmd(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	var opts RenderOptions
+	fs.StringVar(&opts.Workload, "workload", "", "kube-burner workload config to render")
+	fs.IntVar(&opts.Scale, "scale", env_int("SCALE", 1), "scale factor of the workload")
+	fs.BoolVar(&opts.BFD, "bfd", env_default("BFD", "false") == "true", "enable bfd on the serving pods")
+	fs.IntVar(&opts.QPS, "qps", env_int("QPS", 20), "kube-burner qps")
+	fs.IntVar(&opts.Burst, "burst", env_int("BURST", 20), "kube-burner burst")
+	fs.IntVar(&opts.Iterations, "iterations", 0, "override jobIterations of every create job, 0 keeps the config values")
+	fs.StringVar(&opts.UUID, "uuid", "render", "uuid value passed to the object templates")
+	var ip_cfg IPPlanConfig
+	ip_plan_flags(fs, &ip_cfg)
+	out := fs.String("out", "rendered", "directory the expanded yaml is written to")
+	by_ns := fs.Bool("namespaces", false, "print totals for every namespace instead of only per kind")
+	fs.Parse(args)
+
+	if opts.Workload == "" {
+		return fmt.Errorf("please provide the workload config using flag '-workload'")
+	}
+	plan, err := new_ip_plan(ip_cfg, 35*opts.Scale, serving_replicas)
+	if err != nil {
+		log_fields("ip plan not used, serving addresses fall back to the template defaults", "error", err)
+	} else {
+		opts.IPPlan = plan
+	}
+	r, err := render_workload(opts)
+	if err != nil {
+		return err
+	}
+	for _, m := range r.Missing {
+		log_fields("object template not found, skipped", "template", m)
+	}
+	err = r.write(*out)
+	if err != nil {
+		return err
+	}
+	log_fields("wrote rendered workload", "dir", *out, "jobs", len(r.Workload.Jobs), "objects", len(r.Objects))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	kinds := r.kind_totals()
+	var names []string
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "KIND\tCOUNT")
+	for _, k := range names {
+		fmt.Fprintf(w, "%s\t%d\n", k, kinds[k])
+	}
+	fmt.Fprintf(w, "Namespace\t%d\n", len(r.Namespaces))
+
+	if *by_ns {
+		totals := r.namespace_totals()
+		var namespaces []string
+		for ns := range totals {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+		fmt.Fprintln(w, "\nNAMESPACE\tOBJECTS")
+		for _, ns := range namespaces {
+			fmt.Fprintf(w, "%s\t%s\n", ns, join_counts(totals[ns]))
+		}
+	} else {
+		// Group namespaces by prefix, e.g. served-ns-1..35, to keep the output short at scale
+		groups := make(map[string]map[string]int)
+		members := make(map[string]map[string]bool)
+		for _, o := range r.Objects {
+			g := namespace_group(o.Namespace)
+			if groups[g] == nil {
+				groups[g] = make(map[string]int)
+				members[g] = make(map[string]bool)
+			}
+			groups[g][o.Kind]++
+			members[g][o.Namespace] = true
+		}
+		var prefixes []string
+		for g := range groups {
+			prefixes = append(prefixes, g)
+		}
+		sort.Strings(prefixes)
+		fmt.Fprintln(w, "\nNAMESPACES\tCOUNT\tOBJECTS")
+		for _, g := range prefixes {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", g, len(members[g]), join_counts(groups[g]))
+		}
+	}
+	return w.Flush()
+}
+
+// Func namespace_group replaces a trailing iteration number of a namespace with a wildcard
+func namespace_group(ns string) string {
+	i := strings.LastIndex(ns, "-")
+	if i < 0 {
+		return ns
+	}
+	if _, err := strconv.Atoi(ns[i+1:]); err != nil {
+		return ns
+	}
+	return ns[:i] + "-*"
+}
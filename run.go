@@ -0,0 +1,635 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct RunConfig holds the parameters of a workload run, defaulted from the same env vars as create_icni2_workload.sh
+type RunConfig struct {
+	Workload        string
+	UUID            string
+	ServingUUID     string
+	Scale           int
+	BFD             bool
+	QPS             int
+	Burst           int
+	KubeBurner      ExecKubeBurner
+	Kubeconfig      string
+	SSHKey          string
+	ServingInit     string
+	SriovPolicy     string
+	MetricsProfile  string
+	VFServingFactor int
+	Pause           time.Duration
+	From            string
+	Timeouts        map[string]time.Duration
+	Gdocs           bool
+	Parent          string
+	Credentials     string
+	IPPlan          IPPlanConfig
+	BFDTimers       BFDTimers
+	BFDMinUp        float64 // fraction of bfd sessions that must be up after the serving init job
+	Leftovers       string  // ask, cleanup, abort or ignore when objects of earlier runs are found
+	NodeSelection   NodeSelection
+	RestoreLabels   bool // restore node labels when a phase fails
+}
+
+// Struct Phase is one step of a workload run
+type Phase struct {
+	Name    string
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Interface Summarizer writes the summary of a finished workload
+type Summarizer interface {
+	Summarize(ctx context.Context, cfg RunConfig) error
+}
+
+// Struct SelfSummarizer runs this binary in its default summary mode
+type SelfSummarizer struct{}
+
+// Func Summarize invokes the summary for the run uuid, uploading to google docs when configured
+func (SelfSummarizer) Summarize(ctx context.Context, cfg RunConfig) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{"-uuid", cfg.UUID, "-workload", cfg.Workload, "-scale", strconv.Itoa(cfg.Scale), "-bfd=" + strconv.FormatBool(cfg.BFD), "-qps", strconv.Itoa(cfg.QPS), "-burst", strconv.Itoa(cfg.Burst), "-kube-burner-release", cfg.KubeBurner.Release}
+	if cfg.Gdocs {
+		args = append(args, "-gdocs", "-parent", cfg.Parent)
+		if cfg.Credentials != "" {
+			args = append(args, "-credentials", cfg.Credentials)
+		}
+	}
+	cmd := exec.CommandContext(ctx, self, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Struct Orchestrator runs the phases of a workload against a cluster
+type Orchestrator struct {
+	Config  RunConfig
+	Cluster Cluster
+	Burner  KubeBurner
+	Summary Summarizer
+	Exec    PodExec
+	State   *RunState
+
+	token          string
+	prometheus_url string
+}
+
+// Func log_fields logs a message followed by key=value pairs
+func log_fields(msg string, kv ...interface{}) {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		v := fmt.Sprint(kv[i+1])
+		if strings.ContainsAny(v, " \"=") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, " %v=%s", kv[i], v)
+	}
+	log.Println(b.String())
+}
+
+// Func env_default returns the env var or a default when unset
+func env_default(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// Func env_int returns the env var as an int or a default when unset or invalid
+func env_int(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Func parse_timeouts parses per phase timeouts such as "mcp-updated=2h,workload=8h"
+func parse_timeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	if s == "" {
+		return timeouts, nil
+	}
+	for _, t := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(t), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("timeout %q is not in the form phase=duration", t)
+		}
+		d, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		timeouts[kv[0]] = d
+	}
+	return timeouts, nil
+}
+
+// Func lb_count returns the number of worker-spk nodes needed for the serving vfs, with a minimum of 4
+func lb_count(scale int, vf_serving_factor int, vfs_per_node int) int {
+	count := (scale*vf_serving_factor + vfs_per_node - 1) / vfs_per_node
+	if count < 4 {
+		count = 4
+	}
+	return count
+}
+
+// Func phases returns the ordered phases of a run with their default timeouts
+func (o *Orchestrator) phases() []Phase {
+	phases := []Phase{
+		{Name: "leftovers", Timeout: 30 * time.Minute, Run: o.leftovers},
+		{Name: "label-nodes", Timeout: 5 * time.Minute, Run: o.label_nodes},
+		{Name: "sriov-policy", Timeout: 10 * time.Minute, Run: o.sriov_policy},
+		{Name: "mcp-updated", Timeout: time.Hour, Run: o.mcp_updated},
+		{Name: "serving-init", Timeout: time.Hour, Run: o.serving_init},
+		{Name: "bfd-check", Timeout: 15 * time.Minute, Run: o.bfd_check},
+		{Name: "workload", Timeout: 6 * time.Hour, Run: o.workload},
+		{Name: "summary", Timeout: 30 * time.Minute, Run: o.summary},
+	}
+	for i, p := range phases {
+		if t, ok := o.Config.Timeouts[p.Name]; ok {
+			phases[i].Timeout = t
+		}
+	}
+	return phases
+}
+
+// Func Run executes the phases in order, starting at Config.From when set and skipping phases already completed in State
+func (o *Orchestrator) Run(ctx context.Context) error {
+	phases := o.phases()
+	start := 0
+	if o.Config.From != "" {
+		start = -1
+		for i, p := range phases {
+			if p.Name == o.Config.From {
+				start = i
+			}
+		}
+		if start < 0 {
+			return fmt.Errorf("unknown phase %q given with flag 'from'", o.Config.From)
+		}
+	}
+	if o.State == nil {
+		o.State = &RunState{UUID: o.Config.UUID, Config: o.Config}
+	}
+	err := o.record("running", nil)
+	if err != nil {
+		return err
+	}
+	for _, p := range phases[start:] {
+		if o.State.done(p.Name) {
+			log_fields("phase already completed, skipping", "phase", p.Name, "uuid", o.Config.UUID)
+			continue
+		}
+		log_fields("phase started", "phase", p.Name, "uuid", o.Config.UUID, "timeout", p.Timeout)
+		begin := time.Now()
+		pctx, cancel := context.WithTimeout(ctx, p.Timeout)
+		err = p.Run(pctx)
+		cancel()
+		if err != nil {
+			log_fields("phase failed", "phase", p.Name, "uuid", o.Config.UUID, "duration", time.Since(begin).Round(time.Second), "error", err)
+			if serr := o.State.fail(p.Name, err); serr != nil {
+				log_fields("unable to save run state", "uuid", o.Config.UUID, "error", serr)
+			}
+			if o.Config.RestoreLabels {
+				o.restore_labels()
+			}
+			if rerr := o.record("failed", err); rerr != nil {
+				log_fields("unable to record run", "uuid", o.Config.UUID, "error", rerr)
+			}
+			return fmt.Errorf("phase %s failed: %v, continue with flag '-resume %s'", p.Name, err, o.Config.UUID)
+		}
+		log_fields("phase completed", "phase", p.Name, "uuid", o.Config.UUID, "duration", time.Since(begin).Round(time.Second))
+		err = o.State.complete(p.Name, begin)
+		if err != nil {
+			return err
+		}
+	}
+	return o.record("completed", nil)
+}
+
+// Func record updates the registry entry of the run, keeping the start time of earlier attempts
+func (o *Orchestrator) record(status string, run_err error) error {
+	records, err := load_registry()
+	if err != nil {
+		return err
+	}
+	r := RunRecord{
+		UUID:              o.Config.UUID,
+		ServingUUID:       o.Config.ServingUUID,
+		Workload:          o.Config.Workload,
+		Scale:             o.Config.Scale,
+		BFD:               o.Config.BFD,
+		QPS:               o.Config.QPS,
+		Burst:             o.Config.Burst,
+		KubeBurnerRelease: o.Config.KubeBurner.Release,
+		Start:             time.Now(),
+		Status:            status,
+	}
+	for _, existing := range records {
+		if existing.UUID == r.UUID {
+			r.Start = existing.Start
+			r.KubeBurnerVersion = existing.KubeBurnerVersion
+		}
+	}
+	if r.KubeBurnerVersion == "" {
+		version, err := o.Burner.Version(context.Background())
+		if err != nil {
+			log_fields("unable to read kube-burner version", "error", err)
+		}
+		r.KubeBurnerVersion = version
+	}
+	if status != "running" {
+		end := time.Now()
+		r.End = &end
+	}
+	if run_err != nil {
+		r.Error = run_err.Error()
+	}
+	if o.State != nil && o.State.BFD != nil {
+		r.BFDSessions = o.State.BFD.Namespaces
+	}
+	if o.State != nil {
+		r.KubeBurner = o.State.KubeBurner
+	}
+	return record_run(r)
+}
+
+// Func pause waits for the configured pause or until the context is done
+func (o *Orchestrator) pause(ctx context.Context, reason string) error {
+	if o.Config.Pause <= 0 {
+		return nil
+	}
+	log_fields("pausing", "reason", reason, "duration", o.Config.Pause)
+	select {
+	case <-time.After(o.Config.Pause):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Func prometheus looks up the prometheus route and token once per run
+func (o *Orchestrator) prometheus(ctx context.Context) error {
+	if o.token != "" {
+		return nil
+	}
+	url, err := o.Cluster.PrometheusURL(ctx)
+	if err != nil {
+		return err
+	}
+	token, err := o.Cluster.PrometheusToken(ctx)
+	if err != nil {
+		return err
+	}
+	o.prometheus_url = "https://" + url
+	o.token = token
+	return nil
+}
+
+// Func burner_env returns the env vars read by the workload templates
+func (o *Orchestrator) burner_env() []string {
+	return []string{
+		"SCALE=" + strconv.Itoa(o.Config.Scale),
+		"BFD=" + strconv.FormatBool(o.Config.BFD),
+		"QPS=" + strconv.Itoa(o.Config.QPS),
+		"BURST=" + strconv.Itoa(o.Config.Burst),
+		"KUBECONFIG=" + o.Config.Kubeconfig,
+	}
+}
+
+// Func ip_plan allocates the serving and peer addresses of the run
+func (o *Orchestrator) ip_plan() (*IPPlan, error) {
+	return new_ip_plan(o.Config.IPPlan, 35*o.Config.Scale, serving_replicas)
+}
+
+// Func label_nodes labels enough workers, excluding worker-lb, as worker-spk for the serving vfs
+func (o *Orchestrator) label_nodes(ctx context.Context) error {
+	policy, err := read_sriov_policy(o.Config.SriovPolicy)
+	if err != nil {
+		return err
+	}
+	nodes, err := o.Cluster.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	var load map[string]int
+	if o.Config.NodeSelection.Policy == "least-loaded" {
+		load, err = o.Cluster.NodePods(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	// Check the vfs fit before labeling anything
+	plan, err := new_sriov_plan(nodes, o.Config.Scale, o.Config.VFServingFactor, policy, o.Config.NodeSelection, load)
+	if err != nil {
+		return err
+	}
+	if len(plan.Label) == 0 {
+		log_fields("found enough worker-spk nodes", "have", len(plan.Existing), "need", plan.LBCount)
+		return nil
+	}
+	labeler := &NodeLabeler{Cluster: o.Cluster, State: o.State}
+	for _, n := range nodes {
+		if exists(plan.Label, n.Name) {
+			err := labeler.Add(ctx, n, worker_spk_label, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Func restore_labels puts back the node labels the run changed after a failure, so a resume labels nodes again
+func (o *Orchestrator) restore_labels() {
+	labeler := &NodeLabeler{Cluster: o.Cluster, State: o.State}
+	// The failed phase may have run out of time, restoring gets its own
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	restored, err := labeler.Restore(ctx)
+	if err != nil {
+		log_fields("unable to restore node labels", "uuid", o.Config.UUID, "error", err)
+		return
+	}
+	if len(restored) == 0 {
+		return
+	}
+	log_fields("restored node labels", "uuid", o.Config.UUID, "nodes", strings.Join(restored, ","))
+	// The sr-iov policy, mcp rollout and everything after ran on the labeled nodes, a resume redoes them from label-nodes
+	var redo []string
+	after := false
+	for _, p := range o.phases() {
+		after = after || p.Name == "label-nodes"
+		if after {
+			redo = append(redo, p.Name)
+		}
+	}
+	var completed []PhaseRecord
+	for _, p := range o.State.Completed {
+		if !exists(redo, p.Name) {
+			completed = append(completed, p)
+		}
+	}
+	o.State.Completed = completed
+	if err := o.State.save(); err != nil {
+		log_fields("unable to save run state", "uuid", o.Config.UUID, "error", err)
+	}
+}
+
+// Func sriov_policy finds the pf shared by the worker-spk nodes and applies the sr-iov network node policy
+func (o *Orchestrator) sriov_policy(ctx context.Context) error {
+	nodes, err := o.Cluster.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	sriov_nic := ""
+	for _, n := range nodes {
+		if !n.has_role("worker-spk") {
+			continue
+		}
+		nic, err := o.Cluster.SriovPF(ctx, n.Name)
+		if err != nil {
+			return err
+		}
+		if sriov_nic == "" {
+			log_fields("setting sr-iov pf", "pf", nic, "node", n.Name)
+			sriov_nic = nic
+		} else if sriov_nic != nic {
+			return fmt.Errorf("sr-iov pf %s on node %s does not match %s on other worker-spk nodes", nic, n.Name, sriov_nic)
+		}
+	}
+	if sriov_nic == "" {
+		return fmt.Errorf("no worker-spk nodes found to read the sr-iov pf from")
+	}
+
+	policy, err := bundle_file(o.Config.SriovPolicy)
+	if err != nil {
+		return err
+	}
+	manifest := os.Expand(string(policy), func(key string) string {
+		if key == "sriov_nic" {
+			return sriov_nic
+		}
+		return os.Getenv(key)
+	})
+	log_fields("creating sriov network node policy", "file", o.Config.SriovPolicy)
+	err = o.Cluster.Apply(ctx, []byte(manifest))
+	if err != nil {
+		return err
+	}
+	return o.pause(ctx, "sr-iov policy rollout")
+}
+
+// Func mcp_updated waits for the machine config pools to finish rolling out the sr-iov policy
+func (o *Orchestrator) mcp_updated(ctx context.Context) error {
+	log_fields("waiting for machine config pools to be updated")
+	return o.Cluster.WaitMCPsUpdated(ctx)
+}
+
+// Func serving_init creates the serving namespaces, sr-iov networks and fake spk pods
+func (o *Orchestrator) serving_init(ctx context.Context) error {
+	secret, err := o.Cluster.KubeconfigSecret(ctx)
+	if err != nil {
+		return err
+	}
+	err = materialize(o.Config.ServingInit)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll("objectTemplates", 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile("objectTemplates/secret_kubeconfig.yaml", secret, 0600)
+	if err != nil {
+		return err
+	}
+	err = o.prometheus(ctx)
+	if err != nil {
+		return err
+	}
+	plan, err := o.ip_plan()
+	if err != nil {
+		return err
+	}
+	err = o.kube_burner(ctx, "serving-init", KubeBurnerOptions{
+		Config: o.Config.ServingInit,
+		UUID:   o.Config.ServingUUID,
+		Token:  o.token,
+		Env:    append(append(o.burner_env(), plan.env()...), o.Config.BFDTimers.env()...),
+	})
+	if err != nil {
+		return err
+	}
+	return o.pause(ctx, "serving pods settle before the workload")
+}
+
+// Func workload runs the kube-burner workload config with metrics collection
+func (o *Orchestrator) workload(ctx context.Context) error {
+	err := materialize(o.Config.Workload, o.Config.MetricsProfile)
+	if err != nil {
+		return err
+	}
+	err = o.prometheus(ctx)
+	if err != nil {
+		return err
+	}
+	return o.kube_burner(ctx, "workload", KubeBurnerOptions{
+		Config:         o.Config.Workload,
+		UUID:           o.Config.UUID,
+		Token:          o.token,
+		PrometheusURL:  o.prometheus_url,
+		MetricsProfile: o.Config.MetricsProfile,
+		Env:            o.burner_env(),
+	})
+}
+
+// Func kube_burner runs kube-burner for a phase with its log in the run directory and records the result in the run state
+func (o *Orchestrator) kube_burner(ctx context.Context, phase string, opts KubeBurnerOptions) error {
+	opts.LogFile = filepath.Join(runs_dir, o.Config.UUID, "kube-burner-"+phase+".log")
+	result, err := o.Burner.Init(ctx, opts)
+	for _, j := range result.Jobs {
+		log_fields("kube-burner job finished", "phase", phase, "job", j.Name, "seconds", j.Seconds)
+	}
+	o.State.KubeBurner = append(o.State.KubeBurner, PhaseKubeBurner{Phase: phase, KubeBurnerResult: result})
+	if serr := o.State.save(); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// Func summary writes the summary of the workload metrics
+func (o *Orchestrator) summary(ctx context.Context) error {
+	return o.Summary.Summarize(ctx, o.Config)
+}
+
+// Func run_cmd parses the run subcommand flags and runs the workload orchestration
+func run_cmd(args []string) error {
+	var cfg RunConfig
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.StringVar(&cfg.Workload, "workload", "", "kube-burner workload config to run")
+	fs.StringVar(&cfg.UUID, "uuid", os.Getenv("UUID"), "uuid used for the workload, generated when not given")
+	fs.IntVar(&cfg.Scale, "scale", env_int("SCALE", 1), "scale factor of the workload")
+	fs.BoolVar(&cfg.BFD, "bfd", env_default("BFD", "false") == "true", "enable bfd on the serving pods")
+	fs.IntVar(&cfg.QPS, "qps", env_int("QPS", 20), "kube-burner qps")
+	fs.IntVar(&cfg.Burst, "burst", env_int("BURST", 20), "kube-burner burst")
+	kube_burner_flags(fs, &cfg.KubeBurner)
+	fs.StringVar(&cfg.Kubeconfig, "kubeconfig", env_default("KUBECONFIG", "/home/kni/clusterconfigs/auth/kubeconfig"), "kubeconfig of the cluster")
+	fs.StringVar(&cfg.SSHKey, "ssh-key", "/home/kni/.ssh/id_rsa", "ssh key used to read the sr-iov pf from the worker-spk nodes")
+	fs.StringVar(&cfg.ServingInit, "serving-init", "workload/cfg_icni2_serving_resource_init.yml", "kube-burner config creating the serving resources")
+	fs.StringVar(&cfg.SriovPolicy, "sriov-policy", "workload/sriov_policy.yaml", "sr-iov network node policy template")
+	fs.StringVar(&cfg.MetricsProfile, "metrics", "workload/metrics_full.yaml", "kube-burner metrics profile")
+	fs.IntVar(&cfg.VFServingFactor, "vf-serving-factor", 140, "vfs needed per unit of scale")
+	fs.DurationVar(&cfg.Pause, "pause", time.Minute, "pause after applying the sr-iov policy and after the serving init job")
+	fs.StringVar(&cfg.From, "from", "", "phase to start from: leftovers, label-nodes, sriov-policy, mcp-updated, serving-init, bfd-check, workload or summary")
+	timeouts := fs.String("timeouts", "", "per phase timeouts, e.g. mcp-updated=2h,workload=8h")
+	resume := fs.String("resume", "", "uuid of a previous run to continue from its first incomplete phase, using its saved parameters")
+	fs.BoolVar(&cfg.Gdocs, "gdocs", env_default("GDOCS", "false") == "true", "push the summary to google docs")
+	fs.StringVar(&cfg.Parent, "parent", os.Getenv("PARENTID"), "google sheet parent id")
+	fs.StringVar(&cfg.Credentials, "credentials", os.Getenv("CREDENTIALS"), "google credentials json file")
+	ip_plan_flags(fs, &cfg.IPPlan)
+	bfd_timer_flags(fs, &cfg.BFDTimers)
+	node_selection_flags(fs, &cfg.NodeSelection)
+	fs.BoolVar(&cfg.RestoreLabels, "restore-labels", false, "restore the node labels the run changed when a phase fails; a resumed run redoes every phase from label-nodes")
+	fs.StringVar(&cfg.Leftovers, "leftovers", "ask", "when objects of earlier runs are found: ask, cleanup, abort or ignore; ask aborts without a terminal")
+	fs.Float64Var(&cfg.BFDMinUp, "bfd-min-up", default_bfd_min_up, "fraction of bfd sessions that must be up after the serving init job")
+	fs.Parse(args)
+
+	t, err := parse_timeouts(*timeouts)
+	if err != nil {
+		return err
+	}
+
+	var state *RunState
+	if *resume != "" {
+		if cfg.From != "" {
+			return fmt.Errorf("flags 'resume' and 'from' cannot be used together")
+		}
+		state, err = load_state(*resume)
+		if err != nil {
+			return err
+		}
+		log_fields("resuming run", "uuid", state.UUID, "completed", len(state.Completed), "failed", state.Failed)
+		cfg = state.Config
+		if cfg.Timeouts == nil {
+			cfg.Timeouts = make(map[string]time.Duration)
+		}
+		for k, v := range t {
+			cfg.Timeouts[k] = v
+		}
+	}
+
+	if cfg.Workload == "" && fs.NArg() > 0 {
+		cfg.Workload = fs.Arg(0)
+	}
+	if cfg.Workload == "" {
+		return fmt.Errorf("please provide kube-burner config using flag '-workload'")
+	}
+	if cfg.UUID == "" {
+		cfg.UUID, err = new_uuid()
+		if err != nil {
+			return err
+		}
+		log_fields("generated workload uuid", "uuid", cfg.UUID)
+	}
+	if cfg.ServingUUID == "" {
+		cfg.ServingUUID, err = new_uuid()
+		if err != nil {
+			return err
+		}
+		log_fields("generated serving init uuid", "uuid", cfg.ServingUUID)
+	}
+	if cfg.Gdocs && cfg.Parent == "" {
+		return fmt.Errorf("google docs set to true with flag 'gdocs', but no parent id given with flag 'parent'")
+	}
+	if state == nil {
+		if _, err := os.Stat(state_file(cfg.UUID)); err == nil {
+			return fmt.Errorf("run state already exists for uuid %s, continue it with flag '-resume %s'", cfg.UUID, cfg.UUID)
+		}
+		cfg.Timeouts = t
+	}
+
+	cluster := &OcCluster{Kubeconfig: cfg.Kubeconfig, SSHKey: cfg.SSHKey}
+	o := &Orchestrator{
+		Config:  cfg,
+		Cluster: cluster,
+		Burner:  cfg.KubeBurner,
+		Summary: SelfSummarizer{},
+		Exec:    cluster,
+		State:   state,
+	}
+	// Fail before touching the cluster when the serving addresses do not fit
+	if _, err := o.ip_plan(); err != nil {
+		return fmt.Errorf("ip plan: %v", err)
+	}
+	if err := cfg.BFDTimers.validate(); err != nil {
+		return err
+	}
+	if err := cfg.NodeSelection.validate(); err != nil {
+		return err
+	}
+	if !exists([]string{"ask", "cleanup", "abort", "ignore"}, cfg.Leftovers) {
+		return fmt.Errorf("unknown value %q for flag 'leftovers', use ask, cleanup, abort or ignore", cfg.Leftovers)
+	}
+	if cfg.BFDMinUp < 0 || cfg.BFDMinUp > 1 {
+		return fmt.Errorf("bfd-min-up %.2f is outside 0-1", cfg.BFDMinUp)
+	}
+	return o.Run(context.Background())
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Struct RunMetadata holds the run parameters and cluster facts added to the summary row
+type RunMetadata struct {
+	Workload          string
+	Scale             string
+	BFD               string
+	QPS               string
+	Burst             string
+	KubeBurnerRelease string
+	ClusterVersion    string
+	NodeRoles         string
+	NodeStatus        string
+}
+
+// Summary columns filled from RunMetadata, in the order of RunMetadata.row
+var metadata_header = []string{"Workload", "Scale", "BFD", "QPS", "Burst", "KubeBurnerRelease", "ClusterVersion", "NodeRoles", "NodeStatus"}
+
+// Struct JsonStructLabels is used for metric files where the labels carry the data, such as clusterVersion
+type JsonStructLabels struct {
+	Timestamp  string            `json:"timestamp"`
+	Labels     map[string]string `json:"labels"`
+	Value      float64           `json:"value"`
+	UUID       string            `json:"uuid"`
+	MetricName string            `json:"metricName"`
+}
+
+// Func row returns the metadata values in the order of metadata_header
+func (m RunMetadata) row() []string {
+	return []string{m.Workload, m.Scale, m.BFD, m.QPS, m.Burst, m.KubeBurnerRelease, m.ClusterVersion, m.NodeRoles, m.NodeStatus}
+}
+
+// Func metadata_from_record fills run parameters from a registry entry or run manifest
+func metadata_from_record(r RunRecord) RunMetadata {
+	return RunMetadata{
+		Workload:          r.Workload,
+		Scale:             strconv.Itoa(r.Scale),
+		BFD:               strconv.FormatBool(r.BFD),
+		QPS:               strconv.Itoa(r.QPS),
+		Burst:             strconv.Itoa(r.Burst),
+		KubeBurnerRelease: r.KubeBurnerRelease,
+	}
+}
+
+// Func load_manifest reads a run manifest, which uses the same json fields as the run registry
+func load_manifest(path string) (RunRecord, error) {
+	var r RunRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return r, fmt.Errorf("unable to parse run manifest %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// Func run_metadata resolves run parameters from the manifest, or the registry entry for uuid when no manifest is given
+func run_metadata(uuid string, manifest string) (RunMetadata, error) {
+	if manifest != "" {
+		r, err := load_manifest(manifest)
+		if err != nil {
+			return RunMetadata{}, err
+		}
+		return metadata_from_record(r), nil
+	}
+	records, err := load_registry()
+	if err != nil {
+		return RunMetadata{}, err
+	}
+	for _, r := range records {
+		if r.UUID == uuid {
+			log.Println("Found run parameters for uuid", uuid, "in", registry_file())
+			return metadata_from_record(r), nil
+		}
+	}
+	return RunMetadata{}, nil
+}
+
+// Func cluster_facts fills cluster version, node roles and node status from the collected metrics of uuid
+func cluster_facts(wd string, uuid string, m *RunMetadata) {
+	for _, name := range []string{"clusterVersion", "nodeRoles", "nodeStatus"} {
+		files := retrieve_json_files([]string{name}, uuid)
+		if len(files) == 0 {
+			continue
+		}
+		// ls may return several lines, use the first match
+		file := strings.TrimSpace(strings.Split(files[0], "\n")[0])
+		data, err := ioutil.ReadFile(filepath.Join(wd, "collected-metrics", file))
+		if err != nil {
+			log.Println("Problem reading json file", file, "with error", err)
+			continue
+		}
+		var docs []JsonStructLabels
+		err = json.Unmarshal(data, &docs)
+		if err != nil {
+			log.Println("Problem parsing json file", file, "with error", err)
+			continue
+		}
+		switch name {
+		case "clusterVersion":
+			for _, d := range docs {
+				if v := d.Labels["version"]; v != "" {
+					m.ClusterVersion = v
+				}
+			}
+		case "nodeRoles":
+			roles := make(map[string]map[string]bool)
+			for _, d := range docs {
+				role := d.Labels["role"]
+				if role == "" {
+					continue
+				}
+				if roles[role] == nil {
+					roles[role] = make(map[string]bool)
+				}
+				roles[role][d.Labels["node"]] = true
+			}
+			counts := make(map[string]int)
+			for role, nodes := range roles {
+				counts[role] = len(nodes)
+			}
+			m.NodeRoles = join_counts(counts)
+		case "nodeStatus":
+			// Range query, keep the latest value for each condition
+			latest := make(map[string]JsonStructLabels)
+			for _, d := range docs {
+				c := d.Labels["condition"]
+				if c == "" {
+					continue
+				}
+				if prev, ok := latest[c]; !ok || d.Timestamp >= prev.Timestamp {
+					latest[c] = d
+				}
+			}
+			counts := make(map[string]int)
+			for c, d := range latest {
+				counts[c] = int(d.Value)
+			}
+			m.NodeStatus = join_counts(counts)
+		}
+	}
+}
+
+// Func join_counts formats counts as sorted key=value pairs
+func join_counts(counts map[string]int) string {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, k+"="+strconv.Itoa(counts[k]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Struct FakeCluster records the operations called on it and answers from canned nodes
+type FakeCluster struct {
+	Nodes_    []Node
+	Load      map[string]int
+	Leftovers []LabeledObject
+	Objects   map[string][]string // objects listed per "resource selector"
+	Pools     []MachineConfigPool
+	CSVs      map[string][]OperatorCSV // operator csvs per namespace
+	NS        []Namespace
+	PFs       map[string]string // sr-iov pf per node, ens1f0 when unset
+	Errors    map[string]error  // error returned by an operation
+	Block     map[string]bool   // operations that wait for the context to be done
+	Calls     []string
+	Labels    []string // node=label arguments of LabelNode
+	Deleted   []string // resources of DeleteObjects, in order
+}
+
+// Func call records an operation and returns its canned error
+func (c *FakeCluster) call(ctx context.Context, name string) error {
+	c.Calls = append(c.Calls, name)
+	if c.Block[name] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return c.Errors[name]
+}
+
+func (c *FakeCluster) Nodes(ctx context.Context) ([]Node, error) {
+	return c.Nodes_, c.call(ctx, "Nodes")
+}
+
+func (c *FakeCluster) LabelNode(ctx context.Context, node string, label string) error {
+	c.Labels = append(c.Labels, node+" "+label)
+	return c.call(ctx, "LabelNode")
+}
+
+func (c *FakeCluster) SriovPF(ctx context.Context, node string) (string, error) {
+	pf, ok := c.PFs[node]
+	if !ok {
+		pf = "ens1f0"
+	}
+	return pf, c.call(ctx, "SriovPF")
+}
+
+func (c *FakeCluster) Apply(ctx context.Context, manifest []byte) error {
+	return c.call(ctx, "Apply")
+}
+
+func (c *FakeCluster) WaitMCPsUpdated(ctx context.Context) error {
+	return c.call(ctx, "WaitMCPsUpdated")
+}
+
+func (c *FakeCluster) PrometheusURL(ctx context.Context) (string, error) {
+	return "prometheus.example", c.call(ctx, "PrometheusURL")
+}
+
+func (c *FakeCluster) PrometheusToken(ctx context.Context) (string, error) {
+	return "token", c.call(ctx, "PrometheusToken")
+}
+
+func (c *FakeCluster) KubeconfigSecret(ctx context.Context) ([]byte, error) {
+	return []byte("kind: Secret\n"), c.call(ctx, "KubeconfigSecret")
+}
+
+func (c *FakeCluster) ListObjects(ctx context.Context, resource string, selector string) ([]string, error) {
+	return c.Objects[resource+" "+selector], c.call(ctx, "ListObjects")
+}
+
+func (c *FakeCluster) MachineConfigPools(ctx context.Context) ([]MachineConfigPool, error) {
+	return c.Pools, c.call(ctx, "MachineConfigPools")
+}
+
+func (c *FakeCluster) OperatorCSVs(ctx context.Context, namespace string) ([]OperatorCSV, error) {
+	return c.CSVs[namespace], c.call(ctx, "OperatorCSVs")
+}
+
+func (c *FakeCluster) Namespaces(ctx context.Context, selector string) ([]Namespace, error) {
+	return c.NS, c.call(ctx, "Namespaces")
+}
+
+func (c *FakeCluster) LabeledObjects(ctx context.Context, resource string, selector string, key string) ([]LabeledObject, error) {
+	var objects []LabeledObject
+	for _, o := range c.Leftovers {
+		// Objects are given as resource/namespace/name, a selector only lists labeled ones
+		if strings.HasPrefix(o.Object, resource+"/") && (selector == "" || o.Value != "") {
+			objects = append(objects, o)
+		}
+	}
+	return objects, c.call(ctx, "LabeledObjects")
+}
+
+func (c *FakeCluster) DeleteObjects(ctx context.Context, resource string, objects []string) error {
+	c.Deleted = append(c.Deleted, resource)
+	return c.call(ctx, "DeleteObjects")
+}
+
+func (c *FakeCluster) NodePods(ctx context.Context) (map[string]int, error) {
+	return c.Load, c.call(ctx, "NodePods")
+}
+
+// Func called checks if an operation was called
+func (c *FakeCluster) called(name string) bool {
+	return exists(c.Calls, name)
+}
+
+// Struct FakeKubeBurner records the configs it was run with
+type FakeKubeBurner struct {
+	Configs []string
+	Errors  map[string]error // error returned per config
+}
+
+func (b *FakeKubeBurner) Init(ctx context.Context, opts KubeBurnerOptions) (KubeBurnerResult, error) {
+	b.Configs = append(b.Configs, opts.Config)
+	result := KubeBurnerResult{Config: opts.Config, UUID: opts.UUID, Start: time.Now(), End: time.Now()}
+	if err := b.Errors[opts.Config]; err != nil {
+		result.ExitCode = 1
+		return result, err
+	}
+	return result, nil
+}
+
+func (b *FakeKubeBurner) Version(ctx context.Context) (string, error) {
+	return "1.0.0-fake", nil
+}
+
+// Struct FakeSummarizer counts the summaries written
+type FakeSummarizer struct {
+	Runs int
+}
+
+func (s *FakeSummarizer) Summarize(ctx context.Context, cfg RunConfig) error {
+	s.Runs++
+	return nil
+}
+
+// Func in_temp_dir runs the test in an empty directory, runs/ and the materialized workload files are written there
+func in_temp_dir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// Func spk_nodes returns ready workers, the first count of them already labeled worker-spk
+func spk_nodes(workers int, count int) []Node {
+	var nodes []Node
+	for i := 0; i < workers; i++ {
+		n := Node{Name: "worker-" + string(rune('a'+i)), Roles: []string{"worker"}, Ready: true, Labels: map[string]string{"node-role.kubernetes.io/worker": ""}}
+		if i < count {
+			n.Roles = append(n.Roles, "worker-spk")
+			n.Labels[worker_spk_label] = ""
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// Func test_orchestrator returns an orchestrator of a scale 1 run against fakes
+func test_orchestrator(cluster *FakeCluster) (*Orchestrator, *FakeKubeBurner, *FakeSummarizer) {
+	burner := &FakeKubeBurner{}
+	summary := &FakeSummarizer{}
+	o := &Orchestrator{
+		Config: RunConfig{
+			Workload:        "workload/cfg_icni2_cluster_density2.yml",
+			UUID:            "test-uuid",
+			ServingUUID:     "serving-uuid",
+			Scale:           1,
+			QPS:             20,
+			Burst:           20,
+			ServingInit:     "workload/cfg_icni2_serving_resource_init.yml",
+			SriovPolicy:     "workload/sriov_policy.yaml",
+			MetricsProfile:  "workload/metrics_full.yaml",
+			VFServingFactor: 140,
+			Leftovers:       "abort",
+			NodeSelection:   NodeSelection{Policy: "first", RackLabel: default_rack_label},
+			BFDMinUp:        default_bfd_min_up,
+			BFDTimers:       default_bfd_timers,
+			IPPlan:          default_ip_plan,
+			Timeouts:        map[string]time.Duration{},
+		},
+		Cluster: cluster,
+		Burner:  burner,
+		Summary: summary,
+	}
+	return o, burner, summary
+}
+
+// Func completed returns the names of the completed phases in order
+func completed(s *RunState) []string {
+	var names []string
+	for _, p := range s.Completed {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// Func phase_names returns the names of all phases in order
+func phase_names(o *Orchestrator) []string {
+	var names []string
+	for _, p := range o.phases() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestRunPhaseOrder(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 4)}
+	o, burner, summary := test_orchestrator(cluster)
+
+	err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"leftovers", "label-nodes", "sriov-policy", "mcp-updated", "serving-init", "bfd-check", "workload", "summary"}
+	if got := completed(o.State); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("completed phases %v, want %v", got, want)
+	}
+	if got := strings.Join(burner.Configs, ","); got != o.Config.ServingInit+","+o.Config.Workload {
+		t.Errorf("kube-burner ran %s, want the serving init job before the workload", got)
+	}
+	if summary.Runs != 1 {
+		t.Errorf("summary ran %d times, want 1", summary.Runs)
+	}
+	if !cluster.called("Apply") || !cluster.called("WaitMCPsUpdated") {
+		t.Errorf("sr-iov policy not applied and waited for: %v", cluster.Calls)
+	}
+	// Enough worker-spk nodes already exist, nothing is labeled
+	if len(cluster.Labels) != 0 {
+		t.Errorf("labeled %v, want no labels", cluster.Labels)
+	}
+
+	saved, err := load_state(o.Config.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Completed) != len(want) || len(saved.KubeBurner) != 2 {
+		t.Errorf("saved state has %d phases and %d kube-burner runs", len(saved.Completed), len(saved.KubeBurner))
+	}
+	records, err := load_registry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Status != "completed" || records[0].End == nil || records[0].KubeBurnerVersion != "1.0.0-fake" {
+		t.Errorf("registry records %+v", records)
+	}
+}
+
+func TestRunPhaseTimeouts(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 4), Block: map[string]bool{"WaitMCPsUpdated": true}}
+	o, burner, _ := test_orchestrator(cluster)
+	o.Config.Timeouts["mcp-updated"] = 20 * time.Millisecond
+
+	for _, p := range o.phases() {
+		if p.Name == "mcp-updated" && p.Timeout != 20*time.Millisecond {
+			t.Errorf("mcp-updated timeout %s, want the configured 20ms", p.Timeout)
+		}
+		if p.Name == "workload" && p.Timeout != 6*time.Hour {
+			t.Errorf("workload timeout %s, want the default 6h", p.Timeout)
+		}
+	}
+
+	begin := time.Now()
+	err := o.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "phase mcp-updated failed") {
+		t.Fatalf("got error %v, want mcp-updated to fail", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) && !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("got error %v, want the phase deadline", err)
+	}
+	if time.Since(begin) > 10*time.Second {
+		t.Errorf("run took %s, the phase timeout was not applied", time.Since(begin))
+	}
+	if len(burner.Configs) != 0 {
+		t.Errorf("kube-burner ran %v after a failed phase", burner.Configs)
+	}
+}
+
+func TestRunFrom(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 4)}
+	o, burner, summary := test_orchestrator(cluster)
+	o.Config.From = "workload"
+
+	err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(completed(o.State), ","); got != "workload,summary" {
+		t.Errorf("completed phases %s, want workload,summary", got)
+	}
+	if len(burner.Configs) != 1 || burner.Configs[0] != o.Config.Workload || summary.Runs != 1 {
+		t.Errorf("kube-burner ran %v and summary %d times", burner.Configs, summary.Runs)
+	}
+	if cluster.called("Nodes") || cluster.called("WaitMCPsUpdated") {
+		t.Errorf("phases before workload called the cluster: %v", cluster.Calls)
+	}
+
+	o, _, _ = test_orchestrator(&FakeCluster{})
+	o.Config.From = "no-such-phase"
+	if err := o.Run(context.Background()); err == nil {
+		t.Error("expected an error for an unknown phase")
+	}
+}
+
+func TestRunResumeSkipsCompleted(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 4)}
+	o, burner, summary := test_orchestrator(cluster)
+	o.State = &RunState{UUID: o.Config.UUID, Config: o.Config, Failed: "workload", Error: "kube-burner exited with status 1"}
+	for _, name := range phase_names(o)[:6] {
+		o.State.Completed = append(o.State.Completed, PhaseRecord{Name: name})
+	}
+
+	err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"LabeledObjects", "Nodes", "Apply", "WaitMCPsUpdated", "KubeconfigSecret"} {
+		if cluster.called(op) {
+			t.Errorf("completed phases called %s again: %v", op, cluster.Calls)
+		}
+	}
+	if len(burner.Configs) != 1 || burner.Configs[0] != o.Config.Workload || summary.Runs != 1 {
+		t.Errorf("kube-burner ran %v and summary %d times", burner.Configs, summary.Runs)
+	}
+	if o.State.Failed != "" || o.State.Error != "" {
+		t.Errorf("failure %s: %s not cleared after the resumed run completed", o.State.Failed, o.State.Error)
+	}
+}
+
+func TestRunFailureRecordsState(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 4)}
+	o, burner, summary := test_orchestrator(cluster)
+	burner.Errors = map[string]error{o.Config.Workload: errors.New("kube-burner exited with status 1")}
+
+	err := o.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "-resume test-uuid") {
+		t.Fatalf("got error %v, want the workload to fail with a resume hint", err)
+	}
+	if summary.Runs != 0 {
+		t.Error("summary ran after the workload failed")
+	}
+
+	saved, err := load_state(o.Config.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Failed != "workload" || !strings.Contains(saved.Error, "status 1") {
+		t.Errorf("saved failure %q: %q", saved.Failed, saved.Error)
+	}
+	if got := strings.Join(completed(saved), ","); got != strings.Join(phase_names(o)[:6], ",") {
+		t.Errorf("saved completed phases %s", got)
+	}
+	if len(saved.KubeBurner) != 2 || saved.KubeBurner[1].ExitCode != 1 {
+		t.Errorf("saved kube-burner results %+v", saved.KubeBurner)
+	}
+	records, err := load_registry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Status != "failed" || !strings.Contains(records[0].Error, "status 1") {
+		t.Errorf("registry records %+v", records)
+	}
+}
+
+func TestRunFailureRestoresLabels(t *testing.T) {
+	in_temp_dir(t)
+	cluster := &FakeCluster{Nodes_: spk_nodes(6, 2)}
+	o, burner, _ := test_orchestrator(cluster)
+	o.Config.RestoreLabels = true
+	burner.Errors = map[string]error{o.Config.Workload: errors.New("kube-burner exited with status 1")}
+
+	if err := o.Run(context.Background()); err == nil {
+		t.Fatal("workload failure not returned")
+	}
+	want := []string{
+		"worker-c " + worker_spk_label + "=", "worker-d " + worker_spk_label + "=",
+		"worker-d " + worker_spk_label + "-", "worker-c " + worker_spk_label + "-",
+	}
+	if strings.Join(cluster.Labels, ",") != strings.Join(want, ",") {
+		t.Errorf("label calls %v, want %v", cluster.Labels, want)
+	}
+
+	// Every phase from label-nodes ran on the restored labels, a resume redoes them
+	saved, err := load_state(o.Config.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(completed(saved), ","); got != "leftovers" {
+		t.Errorf("saved completed phases %s, want leftovers", got)
+	}
+	if len(saved.Labels) != 0 {
+		t.Errorf("restored labels still recorded: %+v", saved.Labels)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Windows the summary start and end times can cover
+var summary_windows = []string{"all", "workload"}
+
+// Func widen_window extends the start and end timestamps to include ts, keeping them when ts is not a timestamp
+func widen_window(start string, end string, ts string) (string, string) {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return start, end
+	}
+	if s, err := time.Parse(time.RFC3339Nano, start); err != nil || t.Before(s) {
+		start = ts
+	}
+	if e, err := time.Parse(time.RFC3339Nano, end); err != nil || t.After(e) {
+		end = ts
+	}
+	return start, end
+}
+
+// Struct RunWindow is the part of a run the summary start and end times are trimmed to
+type RunWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Func trim narrows the start and end timestamps to the window, or returns the window when they are empty
+func (w RunWindow) trim(start string, end string) (string, string) {
+	if s, err := time.Parse(time.RFC3339Nano, start); err != nil || s.Before(w.Start) {
+		start = w.Start.UTC().Format(time.RFC3339)
+	}
+	if e, err := time.Parse(time.RFC3339Nano, end); err != nil || e.After(w.End) {
+		end = w.End.UTC().Format(time.RFC3339)
+	}
+	return start, end
+}
+
+// Func contains checks if a sample timestamp is within the window, every sample is when the window is nil
+func (w *RunWindow) contains(ts string) bool {
+	if w == nil {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return false
+	}
+	return !t.Before(w.Start) && !t.After(w.End)
+}
+
+// Func workload_window returns when the workload phase of a run ran, from the run state or else the job times of the workload config
+func workload_window(wd string, uuid string, meta RunMetadata) (*RunWindow, error) {
+	state, err := load_state(uuid)
+	if err == nil {
+		for i := len(state.KubeBurner) - 1; i >= 0; i-- {
+			k := state.KubeBurner[i]
+			if k.Phase == "workload" && !k.Start.IsZero() && !k.End.IsZero() {
+				log.Println("Using the workload phase window of", state_file(uuid))
+				return &RunWindow{Start: k.Start, End: k.End}, nil
+			}
+		}
+	}
+
+	// Runs not started with the run subcommand have no state, use the jobs of the workload config
+	if meta.Workload == "" {
+		return nil, fmt.Errorf("no workload phase in the run state of uuid %s, please provide the workload config using flag '-workload'", uuid)
+	}
+	opts := RenderOptions{Workload: meta.Workload, Scale: 1}
+	if scale, err := strconv.Atoi(meta.Scale); err == nil {
+		opts.Scale = scale
+	}
+	_, cfg, err := render_config(opts)
+	if err != nil {
+		return nil, err
+	}
+	timings, err := job_timings(wd, uuid)
+	if err != nil {
+		return nil, err
+	}
+	var start time.Time
+	var end time.Time
+	for _, t := range timings {
+		found := false
+		for _, job := range cfg.Jobs {
+			if job.Name == t.Job {
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		if start.IsZero() || t.Start.Before(start) {
+			start = t.Start
+		}
+		if t.End.After(end) {
+			end = t.End
+		}
+	}
+	if start.IsZero() {
+		return nil, fmt.Errorf("no job of %s found in collected-metrics or kube-burner logs for uuid %s", meta.Workload, uuid)
+	}
+	log.Println("Using the job times of", meta.Workload, "as workload window")
+	return &RunWindow{Start: start, End: end}, nil
+}
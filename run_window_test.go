@@ -0,0 +1,104 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWidenWindow(t *testing.T) {
+	var start, end string
+	for _, ts := range []string{"2026-10-19T09:10:00Z", "not a time", "2026-10-19T09:00:00.5Z", "", "2026-10-19T11:30:00+02:00", "2026-10-19T09:20:00Z"} {
+		start, end = widen_window(start, end, ts)
+	}
+	if start != "2026-10-19T09:00:00.5Z" || end != "2026-10-19T11:30:00+02:00" {
+		t.Errorf("got %s - %s, want 2026-10-19T09:00:00.5Z - 2026-10-19T11:30:00+02:00", start, end)
+	}
+}
+
+func TestRunWindowTrim(t *testing.T) {
+	w := RunWindow{
+		Start: time.Date(2026, 10, 19, 11, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		End:   time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  [2]string
+	}{
+		{"inside", "2026-10-19T09:15:00Z", "2026-10-19T09:45:00Z", [2]string{"2026-10-19T09:15:00Z", "2026-10-19T09:45:00Z"}},
+		{"wider", "2026-10-19T08:00:00Z", "2026-10-19T12:00:00Z", [2]string{"2026-10-19T09:00:00Z", "2026-10-19T10:00:00Z"}},
+		{"overlapping start", "2026-10-19T08:59:59.9Z", "2026-10-19T09:30:00Z", [2]string{"2026-10-19T09:00:00Z", "2026-10-19T09:30:00Z"}},
+		{"empty", "", "", [2]string{"2026-10-19T09:00:00Z", "2026-10-19T10:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := w.trim(tt.start, tt.end)
+			if start != tt.want[0] || end != tt.want[1] {
+				t.Errorf("got %s - %s, want %s - %s", start, end, tt.want[0], tt.want[1])
+			}
+		})
+	}
+}
+
+func TestWorkloadWindowFromState(t *testing.T) {
+	in_temp_dir(t)
+	capture_log(t)
+	at := func(h int, m int) time.Time { return time.Date(2026, 10, 19, h, m, 0, 0, time.UTC) }
+	state := &RunState{UUID: "u1", KubeBurner: []PhaseKubeBurner{
+		{Phase: "serving-init", KubeBurnerResult: KubeBurnerResult{Start: at(8, 0), End: at(8, 30)}},
+		{Phase: "workload", KubeBurnerResult: KubeBurnerResult{Start: at(9, 0), End: at(9, 20)}},
+		// A resumed workload phase replaces the earlier attempt
+		{Phase: "workload", KubeBurnerResult: KubeBurnerResult{Start: at(9, 40), End: at(10, 30)}},
+		{Phase: "workload", KubeBurnerResult: KubeBurnerResult{Start: at(11, 0)}},
+	}}
+	err := state.save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := workload_window(".", "u1", RunMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Start.Equal(at(9, 40)) || !w.End.Equal(at(10, 30)) {
+		t.Errorf("got window %s - %s, want 09:40 - 10:30", w.Start, w.End)
+	}
+}
+
+func TestWorkloadWindowFromJobs(t *testing.T) {
+	in_temp_dir(t)
+	capture_log(t)
+	workload := "workload/cfg_icni2_node_density2.yml"
+	_, cfg, err := render_config(RenderOptions{Workload: workload, Scale: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := cfg.Jobs[0].Name
+	last := cfg.Jobs[len(cfg.Jobs)-1].Name
+	// The serving init job is not part of the workload config and stays outside the window
+	write_file(t, filepath.Join("collected-metrics", "nodeCPU-u1.json"), `[
+		{"timestamp": "2026-10-19T08:00:00Z", "jobName": "create-cms-job", "value": 1},
+		{"timestamp": "2026-10-19T10:30:00Z", "jobName": "`+last+`", "value": 1},
+		{"timestamp": "2026-10-19T09:00:00Z", "jobName": "`+first+`", "value": 1},
+		{"timestamp": "2026-10-19T09:50:00Z", "jobName": "`+last+`", "value": 1}
+	]`)
+
+	w, err := workload_window(".", "u1", RunMetadata{Workload: workload, Scale: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Start.Format(time.RFC3339) != "2026-10-19T09:00:00Z" || w.End.Format(time.RFC3339) != "2026-10-19T10:30:00Z" {
+		t.Errorf("got window %s - %s, want 09:00 - 10:30", w.Start, w.End)
+	}
+
+	_, err = workload_window(".", "u2", RunMetadata{})
+	if err == nil {
+		t.Error("got a window for a run without state or workload config")
+	}
+	_, err = workload_window(".", "u2", RunMetadata{Workload: workload, Scale: "1"})
+	if err == nil {
+		t.Error("got a window for a run without job times")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Struct SheetNameData holds the fields available to the -sheet-name template
+type SheetNameData struct {
+	Workload string
+	Cluster  string
+	Date     string
+	Week     string
+	Year     string
+	Month    string
+	Day      string
+}
+
+// Default sheet name template for each rollover policy
+var rollover_templates = map[string]string{
+	"daily":        "{{.Date}}",
+	"weekly":       "{{.Week}}",
+	"per-workload": "{{.Workload}}",
+	"per-cluster":  "{{.Cluster}}",
+	"never":        "web-burner",
+}
+
+// Characters not allowed in a sheet key, which names files under gsheet/
+var sheet_key_unsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Func sheet_name_data fills the template fields for the given time, workload config and cluster
+func sheet_name_data(now time.Time, workload string, cluster string) SheetNameData {
+	year, month, day := now.Date()
+	week_year, week := now.ISOWeek()
+	name := strings.TrimSuffix(filepath.Base(workload), filepath.Ext(workload))
+	if workload == "" {
+		name = "unknown-workload"
+	}
+	if cluster == "" {
+		cluster = "unknown-cluster"
+	}
+	return SheetNameData{
+		Workload: name,
+		Cluster:  cluster,
+		Date:     strconv.Itoa(year) + "-" + month.String() + "-" + strconv.Itoa(day),
+		Week:     fmt.Sprintf("%d-W%02d", week_year, week),
+		Year:     strconv.Itoa(year),
+		Month:    month.String(),
+		Day:      strconv.Itoa(day),
+	}
+}
+
+// Func sheet_key renders the name used for the csv, Sheetid and iteration_count files from the rollover policy or template
+func sheet_key(policy string, name_template string, data SheetNameData) (string, error) {
+	if name_template == "" {
+		t, ok := rollover_templates[policy]
+		if !ok {
+			return "", fmt.Errorf("unknown rollover policy %q, expected one of daily, weekly, per-workload, per-cluster, never", policy)
+		}
+		name_template = t
+	}
+	t, err := template.New("sheet-name").Option("missingkey=error").Parse(name_template)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+	// Keep the key usable as a file name, the template fields come from flags and the cluster url
+	key := strings.Trim(sheet_key_unsafe.ReplaceAllString(strings.TrimSpace(buf.String()), "-"), ".-")
+	if key == "" {
+		return "", fmt.Errorf("sheet name template %q rendered an empty name", name_template)
+	}
+	return key, nil
+}
+
+// Func current_cluster returns the api server host of the logged in cluster
+func current_cluster() string {
+	out, err := exec.Command("bash", "-c", "oc whoami --show-server").Output()
+	if err != nil {
+		return ""
+	}
+	s := strings.TrimSpace(string(out))
+	s = strings.TrimPrefix(s, "https://")
+	s = strings.TrimPrefix(s, "api.")
+	return strings.Split(s, ":")[0]
+}
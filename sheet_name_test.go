@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSheetKey(t *testing.T) {
+	data := sheet_name_data(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), "workload/cfg_icni2_cluster_density2.yml", "ocp.example.com")
+	tests := []struct {
+		policy   string
+		template string
+		want     string
+	}{
+		{"daily", "", "2026-October-19"},
+		{"weekly", "", "2026-W43"},
+		{"per-workload", "", "cfg_icni2_cluster_density2"},
+		{"never", "", "web-burner"},
+		{"", "{{.Cluster}}/{{.Workload}} run", "ocp.example.com-cfg_icni2_cluster_density2-run"},
+		// Template values end up in file names under gsheet/, nothing a shell would interpret is kept
+		{"", "a & b; $(rm -rf x) `id` 'q' \"d\" c:d", "a-b-rm--rf-x-id-q-d-c-d"},
+		{"", "../../etc/passwd", "etc-passwd"},
+	}
+	for _, tt := range tests {
+		got, err := sheet_key(tt.policy, tt.template, data)
+		if err != nil {
+			t.Errorf("sheet_key(%q, %q) failed: %v", tt.policy, tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("sheet_key(%q, %q) = %q, want %q", tt.policy, tt.template, got, tt.want)
+		}
+	}
+	for _, tmpl := range []string{"$;&", "{{.Missing}}"} {
+		if _, err := sheet_key("daily", tmpl, data); err == nil {
+			t.Errorf("sheet_key accepted template %q", tmpl)
+		}
+	}
+	if _, err := sheet_key("monthly", "", data); err == nil {
+		t.Error("sheet_key accepted an unknown rollover policy")
+	}
+}
+
+func TestIterationFile(t *testing.T) {
+	dir := t.TempDir()
+	write_file(t, dir+"/gsheet/.keep", "")
+	capture_log(t)
+	for _, want := range []string{"iteration_1", "iteration_2", "iteration_3"} {
+		got, err := iteration(dir, "iteration_count-x.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
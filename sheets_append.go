@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cristoper/gsheet/gdrive"
+	"github.com/cristoper/gsheet/gsheets"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Func align_row orders row to match the header found in the google sheet, extending the sheet header with any new columns
+func align_row(sheet_header []string, header []string, row []string) ([]string, []string) {
+	aligned_header := append([]string{}, sheet_header...)
+	index := make(map[string]int)
+	for i, h := range aligned_header {
+		index[column_name(h)] = i
+	}
+	for _, h := range header {
+		if _, ok := index[h]; !ok {
+			log.Println("Column", h, "not found in google sheet header, adding it")
+			index[h] = len(aligned_header)
+			aligned_header = append(aligned_header, h)
+		}
+	}
+	aligned_row := make([]string, len(aligned_header))
+	for i, h := range header {
+		if i < len(row) {
+			aligned_row[index[h]] = row[i]
+		}
+	}
+	return aligned_header, aligned_row
+}
+
+// Func next_sheet_iteration returns the iteration following the highest one in the iteration column of the sheet rows
+func next_sheet_iteration(sheet_rows [][]string) (string, error) {
+	if len(sheet_rows) == 0 {
+		return "iteration_1", nil
+	}
+	column := -1
+	for i, h := range sheet_rows[0] {
+		if column_name(h) == "Iteration" {
+			column = i
+		}
+	}
+	if column < 0 {
+		return "iteration_1", nil
+	}
+	highest := 0
+	for _, r := range sheet_rows[1:] {
+		if column >= len(r) || !strings.HasPrefix(r[column], "iteration_") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(r[column], "iteration_"))
+		if err != nil {
+			return "", fmt.Errorf("unable to parse iteration %q in google sheet: %v", r[column], err)
+		}
+		if n > highest {
+			highest = n
+		}
+	}
+	return increment_iteration("iteration_" + strconv.Itoa(highest))
+}
+
+// Func append_to_google_sheet appends the newest summary row of the local csv file to the google sheet
+func append_to_google_sheet(wd string, file_name string, parent string, sheet_id string, txt_file string, gdrive_svc *gdrive.Service, gsheet_svc *gsheets.Service, values_svc *sheets.SpreadsheetsValuesService) (string, error) {
+	f := wd + "/gsheet/" + file_name
+	t := wd + "/gsheet/" + txt_file
+
+	if sheet_id == "" {
+		s, err := new_sheet_id(file_name, parent, t, gdrive_svc)
+		if err != nil {
+			return "", err
+		}
+		sheet_id = s
+	}
+
+	r, err := os.Open(f)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	csv_r := csv.NewReader(r)
+	csv_r.FieldsPerRecord = -1
+	rows, err := csv_r.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	if len(rows) < 2 {
+		return "", fmt.Errorf("no summary row found in csv file %s", f)
+	}
+	row := rows[len(rows)-1]
+
+	// Detect the header schema and the iterations already in the sheet
+	resp, err := values_svc.Get(sheet_id, "Sheet1").Do()
+	if err != nil {
+		return "", err
+	}
+	var sheet_rows [][]string
+	for _, r := range resp.Values {
+		var values []string
+		for _, v := range r {
+			values = append(values, fmt.Sprint(v))
+		}
+		sheet_rows = append(sheet_rows, values)
+	}
+	var existing []string
+	if len(sheet_rows) > 0 {
+		existing = sheet_rows[0]
+	}
+
+	// The local csv may have been written with an older header than the current summary
+	header, aligned := align_row(existing, rows[0], row)
+
+	// Others may append to the same sheet, so the iteration follows the sheet rather than the local count
+	next, err := next_sheet_iteration(sheet_rows)
+	if err != nil {
+		return "", err
+	}
+	for i, h := range header {
+		if column_name(h) == "Iteration" {
+			if aligned[i] != next {
+				log.Println("Using", next, "following the last iteration in the google sheet instead of the local", aligned[i])
+			}
+			aligned[i] = next
+		}
+	}
+	if len(header) != len(existing) {
+		log.Println("Writing header to google sheet id", sheet_id)
+		_, err = gsheet_svc.UpdateRangeRaw(sheet_id, "Sheet1!A1", sheet_values([][]string{header})[:1])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.Println("Appending summary row to google sheet id", sheet_id)
+	values := sheet_values([][]string{header, aligned})[1:]
+	_, err = values_svc.Append(sheet_id, "Sheet1!A1", &sheets.ValueRange{MajorDimension: "ROWS", Values: values}).
+		ValueInputOption("RAW").
+		InsertDataOption("INSERT_ROWS").
+		Do()
+	if err != nil {
+		return "", err
+	}
+
+	err = format_sheet(sheet_id, "Sheet1", header, gsheet_svc)
+	if err != nil {
+		return "", err
+	}
+	err = update_charts(sheet_id, "Sheet1", header, gsheet_svc)
+	if err != nil {
+		return "", err
+	}
+	return sheet_id, nil
+}
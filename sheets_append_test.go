@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlignRowOlderCsvHeader(t *testing.T) {
+	sheet_header := []string{"Iteration", "StartTime", "MasterCPU (%)"}
+	csv_header := []string{"Iteration", "StartTime", "MasterCPU", "PodReadyLatencyP99"}
+	row := []string{"iteration_2", "2026-10-19T10:00:00Z", "12.5", "3000"}
+
+	header, aligned := align_row(sheet_header, csv_header, row)
+	if got := strings.Join(header, ","); got != "Iteration,StartTime,MasterCPU (%),PodReadyLatencyP99" {
+		t.Errorf("header = %s", got)
+	}
+	if got := strings.Join(aligned, ","); got != "iteration_2,2026-10-19T10:00:00Z,12.5,3000" {
+		t.Errorf("row = %s", got)
+	}
+
+	// A csv written before PodReadyLatencyP99 leaves the column empty instead of shifting values
+	header, aligned = align_row(header, csv_header[:3], row[:3])
+	if got := strings.Join(aligned, ","); got != "iteration_2,2026-10-19T10:00:00Z,12.5," || len(header) != 4 {
+		t.Errorf("row = %s, header = %v", got, header)
+	}
+}
+
+func TestNextSheetIteration(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want string
+	}{
+		{"empty sheet", nil, "iteration_1"},
+		{"header only", [][]string{{"Iteration", "UUID"}}, "iteration_1"},
+		{"other engineer appended", [][]string{{"UUID", "Iteration"}, {"a", "iteration_1"}, {"b", "iteration_5"}, {"c", "iteration_3"}}, "iteration_6"},
+		{"short rows", [][]string{{"UUID", "Iteration"}, {"a"}, {"b", "iteration_2"}}, "iteration_3"},
+	}
+	for _, tt := range tests {
+		got, err := next_sheet_iteration(tt.rows)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if _, err := next_sheet_iteration([][]string{{"Iteration"}, {"iteration_x"}}); err == nil {
+		t.Error("expected an error for an unparsable iteration")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+
+	"github.com/cristoper/gsheet/gsheets"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Summary columns charted across iterations on the charts tab
+var chart_columns = []string{"MasterCPU", "WorkerCPU", "API99thLatency", "99thEtcdDiskWalFsyncDurationSeconds", "PodReadyLatencyP99"}
+
+// Name of the tab that holds the iteration charts
+const charts_sheet_name = "Charts"
+
+// Func chart_spec builds a line chart of one summary column against the iteration column
+func chart_spec(data_sid int64, header string, column int64) *sheets.ChartSpec {
+	source := func(c int64) *sheets.ChartData {
+		return &sheets.ChartData{
+			SourceRange: &sheets.ChartSourceRange{
+				Sources: []*sheets.GridRange{{SheetId: data_sid, StartColumnIndex: c, EndColumnIndex: c + 1}},
+			},
+		}
+	}
+	return &sheets.ChartSpec{
+		Title: column_name(header) + " by iteration",
+		BasicChart: &sheets.BasicChartSpec{
+			ChartType:      "LINE",
+			LegendPosition: "BOTTOM_LEGEND",
+			HeaderCount:    1,
+			Axis: []*sheets.BasicChartAxis{
+				{Position: "BOTTOM_AXIS", Title: "Iteration"},
+				{Position: "LEFT_AXIS", Title: header},
+			},
+			Domains: []*sheets.BasicChartDomain{{Domain: source(0)}},
+			Series:  []*sheets.BasicChartSeries{{Series: source(column), TargetAxis: "LEFT_AXIS"}},
+		},
+	}
+}
+
+// Func update_charts creates or updates the line charts for chart_columns on the charts tab
+func update_charts(sheet_id string, data_sheet string, header []string, gsheet_svc *gsheets.Service) error {
+	data_sid, err := gsheet_svc.SheetFromTitle(sheet_id, data_sheet)
+	if err != nil {
+		return err
+	}
+	if data_sid == nil {
+		log.Println("No sheet titled", data_sheet, "found, skipping charts")
+		return nil
+	}
+
+	charts_sid, err := gsheet_svc.SheetFromTitle(sheet_id, charts_sheet_name)
+	if err != nil {
+		return err
+	}
+	if charts_sid == nil {
+		log.Println("Creating", charts_sheet_name, "tab in google sheet id", sheet_id)
+		err = gsheet_svc.NewSheet(sheet_id, charts_sheet_name)
+		if err != nil {
+			return err
+		}
+		charts_sid, err = gsheet_svc.SheetFromTitle(sheet_id, charts_sheet_name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Map existing charts by title so reruns update rather than duplicate them
+	ss, err := gsheet_svc.SpreadsheetsService().Get(sheet_id).Fields("sheets(properties,charts)").Do()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]int64)
+	for _, s := range ss.Sheets {
+		if s.Properties.SheetId != *charts_sid {
+			continue
+		}
+		for _, c := range s.Charts {
+			if c.Spec != nil {
+				existing[c.Spec.Title] = c.ChartId
+			}
+		}
+	}
+
+	var requests []*sheets.Request
+	for i, name := range chart_columns {
+		column := int64(-1)
+		for c, h := range header {
+			if column_name(h) == name {
+				column = int64(c)
+			}
+		}
+		if column < 0 {
+			log.Println("Column", name, "not found in sheet header, skipping chart")
+			continue
+		}
+		spec := chart_spec(*data_sid, sheet_header(name), column)
+		if id, ok := existing[spec.Title]; ok {
+			requests = append(requests, &sheets.Request{
+				UpdateChartSpec: &sheets.UpdateChartSpecRequest{ChartId: id, Spec: spec},
+			})
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			AddChart: &sheets.AddChartRequest{
+				Chart: &sheets.EmbeddedChart{
+					Spec: spec,
+					Position: &sheets.EmbeddedObjectPosition{
+						OverlayPosition: &sheets.OverlayPosition{
+							AnchorCell:   &sheets.GridCoordinate{SheetId: *charts_sid, RowIndex: int64(i) * 20},
+							WidthPixels:  800,
+							HeightPixels: 370,
+						},
+					},
+				},
+			},
+		})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	log.Println("Updating", len(requests), "charts on", charts_sheet_name, "tab in google sheet id", sheet_id)
+	_, err = gsheet_svc.SpreadsheetsService().BatchUpdate(sheet_id, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Do()
+	return err
+}
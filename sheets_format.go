@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/cristoper/gsheet/gsheets"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Struct SheetColumn describes how a summary column is presented in the google sheet
+type SheetColumn struct {
+	Unit      string  // unit shown in the header and stripped from the csv value
+	Pattern   string  // number format pattern applied to the column
+	Threshold float64 // values above the threshold are highlighted, 0 disables
+	Regress   string  // "increase" or "decrease" versus the previous iteration is highlighted, "" disables
+}
+
+// Summary columns that are written to the google sheet as numbers
+var sheet_columns = map[string]SheetColumn{
+	"MasterCPU":                           {Unit: "cores", Pattern: "0.00", Regress: "increase"},
+	"WorkerCPU":                           {Unit: "cores", Pattern: "0.00", Regress: "increase"},
+	"MasterMemoryActive":                  {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"WorkerMemoryActive":                  {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"MasterMemoryAvailable":               {Unit: "GB", Pattern: "0.00", Regress: "decrease"},
+	"WorkerMemoryAvailable":               {Unit: "GB", Pattern: "0.00", Regress: "decrease"},
+	"MasterMemoryCached":                  {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"WorkerMemoryCached":                  {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"KubeletCPU":                          {Unit: "%", Pattern: "0.00", Regress: "increase"},
+	"KubeletMemory":                       {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"CrioCPU":                             {Unit: "%", Pattern: "0.00", Regress: "increase"},
+	"CrioMemory":                          {Unit: "GB", Pattern: "0.00", Regress: "increase"},
+	"API99thLatency":                      {Unit: "s", Pattern: "0.000", Threshold: 1, Regress: "increase"},
+	"PodCount":                            {Pattern: "0"},
+	"ServiceCount":                        {Pattern: "0"},
+	"NamespaceCount":                      {Pattern: "0"},
+	"DeploymentCount":                     {Pattern: "0"},
+	"99thEtcdDiskWalFsyncDurationSeconds": {Unit: "s", Pattern: "0.0000", Threshold: 0.01, Regress: "increase"},
+	"EtcdLeaderChangeRate":                {Pattern: "0"},
+	"PodReadyLatencyP99":                  {Unit: "ms", Pattern: "0", Regress: "increase"},
+}
+
+// Func parse_thresholds overrides column thresholds from a list such as "MasterCPU=80,API99thLatency=1"
+func parse_thresholds(thresholds string) error {
+	if thresholds == "" {
+		return nil
+	}
+	for _, t := range strings.Split(thresholds, ",") {
+		kv := strings.SplitN(strings.TrimSpace(t), "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("threshold %q is not in the form Column=value", t)
+		}
+		col, ok := sheet_columns[kv[0]]
+		if !ok {
+			return fmt.Errorf("threshold given for unknown column %q", kv[0])
+		}
+		val, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return err
+		}
+		col.Threshold = val
+		sheet_columns[kv[0]] = col
+	}
+	return nil
+}
+
+// Func sheet_header adds the unit of a column to its header name
+func sheet_header(name string) string {
+	col, ok := sheet_columns[name]
+	if !ok || col.Unit == "" {
+		return name
+	}
+	return name + " (" + col.Unit + ")"
+}
+
+// Func column_name strips the unit from a header name so already uploaded headers can be matched
+func column_name(header string) string {
+	if i := strings.Index(header, " ("); i > 0 && strings.HasSuffix(header, ")") {
+		return header[:i]
+	}
+	return header
+}
+
+// Func sheet_values converts csv rows into typed sheet values with units moved into the header
+func sheet_values(rows [][]string) [][]interface{} {
+	values := make([][]interface{}, len(rows))
+	for r, row := range rows {
+		values[r] = make([]interface{}, len(row))
+		for c, v := range row {
+			if r == 0 {
+				values[r][c] = sheet_header(column_name(v))
+				continue
+			}
+			values[r][c] = v
+			if c >= len(rows[0]) {
+				continue
+			}
+			col, ok := sheet_columns[column_name(rows[0][c])]
+			if !ok {
+				continue
+			}
+			n, err := strconv.ParseFloat(strings.TrimSuffix(v, col.Unit), 64)
+			if err == nil {
+				values[r][c] = n
+			}
+		}
+	}
+	return values
+}
+
+// Func column_letter returns the A1 notation letter(s) for a zero based column index
+func column_letter(c int) string {
+	s := ""
+	for c >= 0 {
+		s = string(rune('A'+c%26)) + s
+		c = c/26 - 1
+	}
+	return s
+}
+
+// Func format_sheet freezes the header, sets number formats, resizes columns and applies conditional formatting
+func format_sheet(sheet_id string, sheet_name string, header []string, gsheet_svc *gsheets.Service) error {
+	sid, err := gsheet_svc.SheetFromTitle(sheet_id, sheet_name)
+	if err != nil {
+		return err
+	}
+	if sid == nil {
+		return fmt.Errorf("no sheet titled %s found in google sheet id %s", sheet_name, sheet_id)
+	}
+
+	ss, err := gsheet_svc.SpreadsheetsService().Get(sheet_id).Fields("sheets(properties,conditionalFormats)").Do()
+	if err != nil {
+		return err
+	}
+
+	var requests []*sheets.Request
+
+	// Remove rules from previous uploads so they are not duplicated
+	for _, s := range ss.Sheets {
+		if s.Properties.SheetId != *sid {
+			continue
+		}
+		for range s.ConditionalFormats {
+			requests = append(requests, &sheets.Request{
+				DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{SheetId: *sid, Index: 0},
+			})
+		}
+	}
+
+	requests = append(requests, &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{SheetId: *sid, GridProperties: &sheets.GridProperties{FrozenRowCount: 1}},
+			Fields:     "gridProperties.frozenRowCount",
+		},
+	})
+
+	for c, h := range header {
+		col, ok := sheet_columns[column_name(h)]
+		if !ok {
+			continue
+		}
+		c64 := int64(c)
+		requests = append(requests, &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range:  &sheets.GridRange{SheetId: *sid, StartRowIndex: 1, StartColumnIndex: c64, EndColumnIndex: c64 + 1},
+				Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: "NUMBER", Pattern: col.Pattern}}},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		})
+		if col.Threshold > 0 {
+			requests = append(requests, &sheets.Request{
+				AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+					Rule: &sheets.ConditionalFormatRule{
+						Ranges: []*sheets.GridRange{{SheetId: *sid, StartRowIndex: 1, StartColumnIndex: c64, EndColumnIndex: c64 + 1}},
+						BooleanRule: &sheets.BooleanRule{
+							Condition: &sheets.BooleanCondition{
+								Type:   "NUMBER_GREATER",
+								Values: []*sheets.ConditionValue{{UserEnteredValue: strconv.FormatFloat(col.Threshold, 'f', -1, 64)}},
+							},
+							Format: &sheets.CellFormat{BackgroundColor: &sheets.Color{Red: 0.96, Green: 0.78, Blue: 0.76}},
+						},
+					},
+				},
+			})
+		}
+		if col.Regress != "" {
+			l := column_letter(c)
+			op := ">"
+			if col.Regress == "decrease" {
+				op = "<"
+			}
+			formula := fmt.Sprintf("=AND(ISNUMBER(%s2),%s3%s%s2*%s)", l, l, op, l, strconv.FormatFloat(regression_factor(col.Regress), 'f', -1, 64))
+			requests = append(requests, &sheets.Request{
+				AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+					Rule: &sheets.ConditionalFormatRule{
+						Ranges: []*sheets.GridRange{{SheetId: *sid, StartRowIndex: 2, StartColumnIndex: c64, EndColumnIndex: c64 + 1}},
+						BooleanRule: &sheets.BooleanRule{
+							Condition: &sheets.BooleanCondition{
+								Type:   "CUSTOM_FORMULA",
+								Values: []*sheets.ConditionValue{{UserEnteredValue: formula}},
+							},
+							Format: &sheets.CellFormat{BackgroundColor: &sheets.Color{Red: 0.99, Green: 0.9, Blue: 0.6}},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	requests = append(requests, &sheets.Request{
+		AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{
+			Dimensions: &sheets.DimensionRange{SheetId: *sid, Dimension: "COLUMNS", EndIndex: int64(len(header))},
+		},
+	})
+
+	log.Println("Applying formatting to sheet", sheet_name, "in google sheet id", sheet_id)
+	_, err = gsheet_svc.SpreadsheetsService().BatchUpdate(sheet_id, &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}).Do()
+	return err
+}
+
+// Func regression_factor returns the multiplier versus the previous iteration that counts as a regression
+func regression_factor(direction string) float64 {
+	if direction == "decrease" {
+		return 1 - regression_pct/100
+	}
+	return 1 + regression_pct/100
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object templates written by create_icni2_workload.sh before the serving init job, not found in the repo
+var runtime_templates = []string{"objectTemplates/secret_kubeconfig.yaml"}
+
+// Annotations whose value is a comma separated list of namespaces that must exist
+var namespace_annotations = []string{"k8s.ovn.org/routing-namespaces"}
+
+// Struct ValidationIssue is a problem found in a workload or object template file
+type ValidationIssue struct {
+	File     string
+	Severity string // error or warning
+	Message  string
+}
+
+// Struct NamespaceRef is a namespace referenced by an annotation of a rendered object
+type NamespaceRef struct {
+	File       string
+	Object     string
+	Annotation string
+	Namespace  string
+}
+
+// Struct Validator collects issues over several workload files, namespace references are checked once all files are rendered
+type Validator struct {
+	Options    RenderOptions
+	Issues     []ValidationIssue
+	Namespaces map[string]bool
+	Refs       []NamespaceRef
+}
+
+// Func errorf records an error for a file
+func (v *Validator) errorf(file string, format string, a ...interface{}) {
+	v.Issues = append(v.Issues, ValidationIssue{File: file, Severity: "error", Message: fmt.Sprintf(format, a...)})
+}
+
+// Func warnf records a warning for a file
+func (v *Validator) warnf(file string, format string, a ...interface{}) {
+	v.Issues = append(v.Issues, ValidationIssue{File: file, Severity: "warning", Message: fmt.Sprintf(format, a...)})
+}
+
+// Func templates checks that every object template parses with the kube-burner function set
+func (v *Validator) templates(files []string) {
+	for _, f := range files {
+		data, err := bundle_file(f)
+		if err != nil {
+			v.errorf(f, "%v", err)
+			continue
+		}
+		_, err = template.New(filepath.Base(f)).Funcs(kube_burner_funcs).Parse(string(data))
+		if err != nil {
+			v.errorf(f, "template does not parse: %v", err)
+		}
+	}
+}
+
+// Func workload renders a workload file and checks its templates and objects
+func (v *Validator) workload(file string) {
+	opts := v.Options
+	opts.Workload = file
+	opts.Strict = true
+	r, err := render_workload(opts)
+	if err != nil {
+		v.errorf(file, "%v", err)
+		return
+	}
+	for _, m := range r.Missing {
+		if exists(runtime_templates, m) {
+			v.warnf(file, "%s is generated at run time by create_icni2_workload.sh, not checked", m)
+			continue
+		}
+		v.errorf(file, "object template %s not found", m)
+	}
+	for ns := range r.Namespaces {
+		v.Namespaces[ns] = true
+	}
+
+	reported := make(map[string]bool)
+	for _, o := range r.Objects {
+		where := fmt.Sprintf("%s (job %s)", o.Template, o.Job)
+		if o.APIVersion == "" || o.Kind == "" || o.Name == "" {
+			// Report each template once, every iteration renders the same fields
+			if !reported[where] {
+				v.errorf(file, "%s renders an object without apiVersion, kind or metadata.name", where)
+				reported[where] = true
+			}
+			continue
+		}
+		var doc interface{}
+		err := yaml.Unmarshal(o.Data, &doc)
+		if err != nil {
+			continue
+		}
+		for _, a := range namespace_annotations {
+			for _, value := range find_key(doc, a) {
+				for _, ns := range strings.Split(value, ",") {
+					ns = strings.TrimSpace(ns)
+					if ns != "" {
+						v.Refs = append(v.Refs, NamespaceRef{File: file, Object: o.Kind + "/" + o.Name, Annotation: a, Namespace: ns})
+					}
+				}
+			}
+		}
+	}
+}
+
+// Func namespace_refs checks that annotated namespaces are created by a job of any validated workload
+func (v *Validator) namespace_refs() {
+	missing := make(map[string][]string)
+	var order []string
+	for _, ref := range v.Refs {
+		if v.Namespaces[ref.Namespace] {
+			continue
+		}
+		key := ref.File + "\x00" + ref.Annotation
+		if _, ok := missing[key]; !ok {
+			order = append(order, key)
+		}
+		if !exists(missing[key], ref.Namespace) {
+			missing[key] = append(missing[key], ref.Namespace)
+		}
+	}
+	for _, key := range order {
+		parts := strings.SplitN(key, "\x00", 2)
+		namespaces := missing[key]
+		sort.Strings(namespaces)
+		shown := namespaces
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		v.errorf(parts[0], "%s refers to %d namespaces no job creates: %s", parts[1], len(namespaces), strings.Join(shown, ", "))
+	}
+}
+
+// Func find_key returns the string values of a key anywhere in a yaml document
+func find_key(doc interface{}, key string) []string {
+	var values []string
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		for k, val := range d {
+			if s, ok := val.(string); ok && k == key {
+				values = append(values, s)
+				continue
+			}
+			values = append(values, find_key(val, key)...)
+		}
+	case []interface{}:
+		for _, val := range d {
+			values = append(values, find_key(val, key)...)
+		}
+	}
+	return values
+}
+
+// Func validate_cmd checks workload configs and object templates without a cluster
+func validate_cmd(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var opts RenderOptions
+	fs.IntVar(&opts.Scale, "scale", env_int("SCALE", 1), "scale factor the workloads are rendered with")
+	fs.BoolVar(&opts.BFD, "bfd", env_default("BFD", "false") == "true", "enable bfd on the serving pods")
+	fs.IntVar(&opts.QPS, "qps", env_int("QPS", 20), "kube-burner qps")
+	fs.IntVar(&opts.Burst, "burst", env_int("BURST", 20), "kube-burner burst")
+	templates := fs.String("templates", "objectTemplates", "directory of object templates parsed even when no workload uses them")
+	var ip_cfg IPPlanConfig
+	ip_plan_flags(fs, &ip_cfg)
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		var err error
+		files, err = bundle_glob("workload/cfg_*.yml")
+		if err != nil {
+			return err
+		}
+	}
+	opts.UUID = "validate"
+	v := &Validator{Options: opts, Namespaces: make(map[string]bool)}
+	plan, err := new_ip_plan(ip_cfg, 35*opts.Scale, serving_replicas)
+	if err != nil {
+		v.errorf("ip plan", "%v", err)
+	} else {
+		v.Options.IPPlan = plan
+	}
+
+	var tmpl []string
+	for _, ext := range []string{"*.yml", "*.yaml"} {
+		m, err := bundle_glob(filepath.Join(*templates, ext))
+		if err != nil {
+			return err
+		}
+		tmpl = append(tmpl, m...)
+	}
+	sort.Strings(tmpl)
+	v.templates(tmpl)
+	for _, f := range files {
+		v.workload(f)
+	}
+	v.namespace_refs()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	errors := 0
+	fmt.Fprintln(w, "FILE\tSEVERITY\tMESSAGE")
+	for _, i := range v.Issues {
+		if i.Severity == "error" {
+			errors++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", i.File, i.Severity, i.Message)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	log_fields("validated workloads", "workloads", len(files), "templates", len(tmpl), "errors", errors, "warnings", len(v.Issues)-errors)
+	if errors > 0 {
+		return fmt.Errorf("%d validation errors", errors)
+	}
+	return nil
+}